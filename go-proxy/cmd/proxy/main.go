@@ -13,7 +13,10 @@ func main() {
 	cfg := config.LoadConfig()
 
 	// Inicjalizacja połączenia z Redis
-	cache.InitRedis(cfg.RedisAddr)
+	cache.InitRedis(cfg.RedisAddr, cfg.LocalCacheTTL, cfg.CacheBackend)
+
+	// Inicjalizacja synchronizacji cache między instancjami (Pub/Sub)
+	cache.InitSync(cfg.RedisSyncEnabled)
 
 	// Uruchomienie serwera proxy
 	http.HandleFunc("/", proxy.ProxyHandler)
@@ -0,0 +1,70 @@
+package cache
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const defaultMemoryCapacity = 1024
+
+// buildStore constructs the Store selected by backendURI, a URI-style value
+// such as:
+//
+//	redis://host:port        - the default rueidis-backed Store (falls back
+//	                            to the bare Redis address if no host is set)
+//	memory://?size=1024       - a size-bounded, TTL-aware LRU, single process
+//	multi://memory,redis      - an in-process LRU (L1) write-through to Redis
+//	                            (L2)
+//
+// multi://'s L2 is a plain go-redis Store rather than the rueidis-backed one
+// redis:// uses: the L1 LRU already serves the same role as rueidis's
+// client-side cache, so stacking both would just be two caches invalidating
+// each other for no benefit, and go-redis doesn't require a real Redis
+// server's RESP3 CLIENT TRACKING support the way rueidis does.
+//
+// An empty backendURI gives the same rueidis-backed Store as redis://, at
+// redisAddr.
+func buildStore(backendURI, redisAddr string, localCacheTTL time.Duration) (Store, error) {
+	if backendURI == "" {
+		return newRueidisStore(redisAddr, localCacheTTL)
+	}
+
+	parsed, err := url.Parse(backendURI)
+	if err != nil {
+		return nil, fmt.Errorf("cache: invalid CACHE_BACKEND %q: %w", backendURI, err)
+	}
+
+	switch parsed.Scheme {
+	case "redis":
+		addr := parsed.Host
+		if addr == "" {
+			addr = redisAddr
+		}
+		return newRueidisStore(addr, localCacheTTL)
+
+	case "memory":
+		return newMemoryStore(ctx, memoryCapacityFrom(parsed)), nil
+
+	case "multi":
+		l2 := newGoredisStore(redisAddr)
+		l1 := newMemoryStore(ctx, memoryCapacityFrom(parsed))
+		return newMultiStore(l1, l2), nil
+
+	default:
+		return nil, fmt.Errorf("cache: unknown CACHE_BACKEND scheme %q", parsed.Scheme)
+	}
+}
+
+func memoryCapacityFrom(u *url.URL) int {
+	raw := u.Query().Get("size")
+	if raw == "" {
+		return defaultMemoryCapacity
+	}
+	size, err := strconv.Atoi(raw)
+	if err != nil || size <= 0 {
+		return defaultMemoryCapacity
+	}
+	return size
+}
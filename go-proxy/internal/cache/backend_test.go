@@ -0,0 +1,49 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestBuildStoreMemory(t *testing.T) {
+	store, err := buildStore("memory://?size=8", "", 0)
+	if err != nil {
+		t.Fatalf("buildStore: %v", err)
+	}
+	if _, ok := store.(*memoryStore); !ok {
+		t.Fatalf("expected *memoryStore, got %T", store)
+	}
+}
+
+func TestBuildStoreMulti(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("failed to start miniredis: %v", err)
+	}
+	defer mr.Close()
+
+	store, err := buildStore("multi://memory,redis", mr.Addr(), time.Second)
+	if err != nil {
+		t.Fatalf("buildStore: %v", err)
+	}
+	if _, ok := store.(*multiStore); !ok {
+		t.Fatalf("expected *multiStore, got %T", store)
+	}
+
+	ctx := context.Background()
+	if err := store.Set(ctx, "foo", "bar", 0); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if got, err := store.Get(ctx, "foo"); err != nil || got != "bar" {
+		t.Fatalf("Get: got %q, err %v", got, err)
+	}
+}
+
+func TestBuildStoreUnknownScheme(t *testing.T) {
+	if _, err := buildStore("bogus://nope", "", 0); err == nil {
+		t.Fatal("expected an error for an unknown CACHE_BACKEND scheme")
+	}
+}
@@ -0,0 +1,32 @@
+package cache
+
+import "time"
+
+// NewMemoryStore builds a size-bounded, in-process LRU Store with capacity
+// entries. It is exported so benchmarks outside this package can compare it
+// against NewGoRedisStore/NewRueidisStore.
+func NewMemoryStore(capacity int) Store {
+	return newMemoryStore(ctx, capacity)
+}
+
+// NewMultiStore builds a two-tier Store: an in-process LRU of capacity
+// entries (L1) write-through to a go-redis-backed Store at addr (L2), the
+// same pairing buildStore uses for CACHE_BACKEND=multi://. It is exported so
+// benchmarks outside this package can compare it against the single-tier
+// stores.
+func NewMultiStore(capacity int, addr string) Store {
+	return newMultiStore(newMemoryStore(ctx, capacity), newGoredisStore(addr))
+}
+
+// NewGoRedisStore builds a go-redis-backed Store for addr. It is exported so
+// benchmarks outside this package can compare it against NewRueidisStore.
+func NewGoRedisStore(addr string) Store {
+	return newGoredisStore(addr)
+}
+
+// NewRueidisStore builds a rueidis-backed Store for addr with client-side
+// caching enabled for localCacheTTL. It is exported so benchmarks outside
+// this package can compare it against NewGoRedisStore.
+func NewRueidisStore(addr string, localCacheTTL time.Duration) (Store, error) {
+	return newRueidisStore(addr, localCacheTTL)
+}
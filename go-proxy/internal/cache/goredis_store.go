@@ -0,0 +1,96 @@
+package cache
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// goredisStore is a Store backed by go-redis, without a client-side cache.
+// redis:// traffic runs on rueidisStore (see rueidis_store.go); this
+// implementation is the L2 tier of multi://, the fallback InitRedis uses
+// when buildStore fails, and the store tests run against miniredis.
+type goredisStore struct {
+	rdb *redis.Client
+}
+
+func newGoredisStore(addr string) *goredisStore {
+	return &goredisStore{rdb: redis.NewClient(&redis.Options{Addr: addr})}
+}
+
+func (s *goredisStore) Get(ctx context.Context, key string) (string, error) {
+	val, err := s.rdb.Get(ctx, key).Result()
+	if err == redis.Nil {
+		return "", ErrNotFound
+	}
+	return val, err
+}
+
+func (s *goredisStore) Set(ctx context.Context, key, value string, ttl time.Duration) error {
+	return s.rdb.Set(ctx, key, value, ttl).Err()
+}
+
+func (s *goredisStore) SetNX(ctx context.Context, key, value string, ttl time.Duration) (bool, error) {
+	return s.rdb.SetNX(ctx, key, value, ttl).Result()
+}
+
+func (s *goredisStore) HGetAll(ctx context.Context, key string) (map[string]string, error) {
+	return s.rdb.HGetAll(ctx, key).Result()
+}
+
+func (s *goredisStore) HSet(ctx context.Context, key string, fields map[string]string) error {
+	args := make(map[string]interface{}, len(fields))
+	for k, v := range fields {
+		args[k] = v
+	}
+	return s.rdb.HSet(ctx, key, args).Err()
+}
+
+func (s *goredisStore) Incr(ctx context.Context, key string) (int64, error) {
+	return s.rdb.Incr(ctx, key).Result()
+}
+
+func (s *goredisStore) Keys(ctx context.Context, pattern string) ([]string, error) {
+	return s.rdb.Keys(ctx, pattern).Result()
+}
+
+func (s *goredisStore) Del(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	return s.rdb.Del(ctx, keys...).Err()
+}
+
+func (s *goredisStore) ListPush(ctx context.Context, key, value string, maxLen int) error {
+	if err := s.rdb.RPush(ctx, key, value).Err(); err != nil {
+		return err
+	}
+	return s.rdb.LTrim(ctx, key, int64(-maxLen), -1).Err()
+}
+
+func (s *goredisStore) ListRange(ctx context.Context, key string) ([]string, error) {
+	return s.rdb.LRange(ctx, key, 0, -1).Result()
+}
+
+func (s *goredisStore) Publish(ctx context.Context, channel, message string) error {
+	return s.rdb.Publish(ctx, channel, message).Err()
+}
+
+func (s *goredisStore) Subscribe(ctx context.Context, channel string) (<-chan string, func(), error) {
+	pubsub := s.rdb.Subscribe(ctx, channel)
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return nil, nil, err
+	}
+
+	out := make(chan string)
+	go func() {
+		defer close(out)
+		for msg := range pubsub.Channel() {
+			out <- msg.Payload
+		}
+	}()
+
+	return out, func() { pubsub.Close() }, nil
+}
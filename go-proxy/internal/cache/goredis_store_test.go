@@ -0,0 +1,66 @@
+package cache
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestGoredisStoreGetSetAndHash(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("failed to start miniredis: %v", err)
+	}
+	defer mr.Close()
+
+	store := newGoredisStore(mr.Addr())
+	ctx := context.Background()
+
+	if _, err := store.Get(ctx, "missing"); err != ErrNotFound {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	if err := store.Set(ctx, "foo", "bar", 0); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if got, err := store.Get(ctx, "foo"); err != nil || got != "bar" {
+		t.Fatalf("Get: got %q, err %v", got, err)
+	}
+
+	if err := store.HSet(ctx, "proxy:/x", map[string]string{"response": "body"}); err != nil {
+		t.Fatalf("HSet: %v", err)
+	}
+	fields, err := store.HGetAll(ctx, "proxy:/x")
+	if err != nil {
+		t.Fatalf("HGetAll: %v", err)
+	}
+	if fields["response"] != "body" {
+		t.Fatalf("unexpected fields: %v", fields)
+	}
+
+	keys, err := store.Keys(ctx, "proxy:*")
+	if err != nil || len(keys) != 1 {
+		t.Fatalf("Keys: got %v, err %v", keys, err)
+	}
+
+	if err := store.Del(ctx, "proxy:/x"); err != nil {
+		t.Fatalf("Del: %v", err)
+	}
+	if keys, _ := store.Keys(ctx, "proxy:*"); len(keys) != 0 {
+		t.Fatalf("expected no keys after Del, got %v", keys)
+	}
+
+	for i := 0; i < 3; i++ {
+		if err := store.ListPush(ctx, "samples", string(rune('a'+i)), 2); err != nil {
+			t.Fatalf("ListPush: %v", err)
+		}
+	}
+	samples, err := store.ListRange(ctx, "samples")
+	if err != nil {
+		t.Fatalf("ListRange: %v", err)
+	}
+	if len(samples) != 2 || samples[0] != "b" || samples[1] != "c" {
+		t.Fatalf("unexpected samples after trim: %v", samples)
+	}
+}
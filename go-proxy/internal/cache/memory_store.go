@@ -0,0 +1,312 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const memoryCleanupInterval = 30 * time.Second
+
+// memoryValue holds a plain string (Get/Set/Incr), a hash (HGetAll/HSet) or
+// a list (ListPush/ListRange); a key is one of these, same as a Redis key.
+type memoryValue struct {
+	isHash bool
+	isList bool
+	str    string
+	hash   map[string]string
+	list   []string
+}
+
+type memoryEntry struct {
+	key       string
+	value     memoryValue
+	expiresAt time.Time // zero means no expiry
+}
+
+// memoryStore is a size-bounded, per-entry-TTL LRU cache used as the L1 tier
+// of the multi-tier provider, or stand-alone via CACHE_BACKEND=memory://.
+// SetNX and Publish/Subscribe are implemented in-process only: there is no
+// shared infrastructure behind a memory store, so cross-instance
+// coordination (request coalescing, cache sync) only applies within the one
+// process holding it.
+type memoryStore struct {
+	capacity int
+
+	mu      sync.Mutex
+	ll      *list.List // front = most recently used
+	entries map[string]*list.Element
+
+	subsMu sync.Mutex
+	subs   map[string][]chan string
+}
+
+// newMemoryStore creates a memory-backed Store with the given capacity and
+// starts a background goroutine that evicts expired entries every
+// memoryCleanupInterval until ctx is canceled.
+func newMemoryStore(ctx context.Context, capacity int) *memoryStore {
+	if capacity <= 0 {
+		capacity = 1024
+	}
+	s := &memoryStore{
+		capacity: capacity,
+		ll:       list.New(),
+		entries:  make(map[string]*list.Element),
+		subs:     make(map[string][]chan string),
+	}
+	go s.cleanupLoop(ctx)
+	return s
+}
+
+func (s *memoryStore) cleanupLoop(ctx context.Context) {
+	ticker := time.NewTicker(memoryCleanupInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.evictExpired()
+		}
+	}
+}
+
+func (s *memoryStore) evictExpired() {
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key, elem := range s.entries {
+		entry := elem.Value.(*memoryEntry)
+		if !entry.expiresAt.IsZero() && now.After(entry.expiresAt) {
+			s.ll.Remove(elem)
+			delete(s.entries, key)
+		}
+	}
+}
+
+// lookupLocked returns the live (non-expired) entry for key, evicting it
+// first if it has expired. Caller must hold s.mu.
+func (s *memoryStore) lookupLocked(key string) (*list.Element, *memoryEntry, bool) {
+	elem, ok := s.entries[key]
+	if !ok {
+		return nil, nil, false
+	}
+	entry := elem.Value.(*memoryEntry)
+	if !entry.expiresAt.IsZero() && time.Now().After(entry.expiresAt) {
+		s.ll.Remove(elem)
+		delete(s.entries, key)
+		return nil, nil, false
+	}
+	return elem, entry, true
+}
+
+func (s *memoryStore) Get(ctx context.Context, key string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	elem, entry, ok := s.lookupLocked(key)
+	if !ok || entry.value.isHash {
+		return "", ErrNotFound
+	}
+	s.ll.MoveToFront(elem)
+	return entry.value.str, nil
+}
+
+func (s *memoryStore) Set(ctx context.Context, key, value string, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.putLocked(key, memoryValue{str: value}, ttl, true)
+	return nil
+}
+
+func (s *memoryStore) SetNX(ctx context.Context, key, value string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, _, ok := s.lookupLocked(key); ok {
+		return false, nil
+	}
+	s.putLocked(key, memoryValue{str: value}, ttl, true)
+	return true, nil
+}
+
+func (s *memoryStore) HGetAll(ctx context.Context, key string) (map[string]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	elem, entry, ok := s.lookupLocked(key)
+	if !ok || !entry.value.isHash {
+		return map[string]string{}, nil
+	}
+	s.ll.MoveToFront(elem)
+
+	fields := make(map[string]string, len(entry.value.hash))
+	for k, v := range entry.value.hash {
+		fields[k] = v
+	}
+	return fields, nil
+}
+
+func (s *memoryStore) HSet(ctx context.Context, key string, fields map[string]string) error {
+	if len(fields) == 0 {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if elem, entry, ok := s.lookupLocked(key); ok && entry.value.isHash {
+		for k, v := range fields {
+			entry.value.hash[k] = v
+		}
+		s.ll.MoveToFront(elem)
+		return nil
+	}
+
+	hash := make(map[string]string, len(fields))
+	for k, v := range fields {
+		hash[k] = v
+	}
+	// HSET never sets or clears a TTL, so a brand-new hash key has none.
+	s.putLocked(key, memoryValue{isHash: true, hash: hash}, 0, true)
+	return nil
+}
+
+func (s *memoryStore) Incr(ctx context.Context, key string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var count int64
+	if _, entry, ok := s.lookupLocked(key); ok && !entry.value.isHash {
+		count, _ = strconv.ParseInt(entry.value.str, 10, 64)
+	}
+	count++
+	s.putLocked(key, memoryValue{str: strconv.FormatInt(count, 10)}, 0, false)
+	return count, nil
+}
+
+// putLocked inserts or overwrites key. If resetTTL is false and key already
+// has an expiry, that expiry is preserved (matches Redis INCR semantics).
+// Caller must hold s.mu.
+func (s *memoryStore) putLocked(key string, value memoryValue, ttl time.Duration, resetTTL bool) {
+	if elem, ok := s.entries[key]; ok {
+		entry := elem.Value.(*memoryEntry)
+		entry.value = value
+		if resetTTL {
+			entry.expiresAt = expiryFor(ttl)
+		}
+		s.ll.MoveToFront(elem)
+		return
+	}
+
+	if s.ll.Len() >= s.capacity {
+		if oldest := s.ll.Back(); oldest != nil {
+			s.ll.Remove(oldest)
+			delete(s.entries, oldest.Value.(*memoryEntry).key)
+		}
+	}
+
+	elem := s.ll.PushFront(&memoryEntry{key: key, value: value, expiresAt: expiryFor(ttl)})
+	s.entries[key] = elem
+}
+
+func expiryFor(ttl time.Duration) time.Time {
+	if ttl <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(ttl)
+}
+
+func (s *memoryStore) ListPush(ctx context.Context, key, value string, maxLen int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var list []string
+	if elem, entry, ok := s.lookupLocked(key); ok && entry.value.isList {
+		list = entry.value.list
+		s.ll.MoveToFront(elem)
+	}
+	list = append(list, value)
+	if maxLen > 0 && len(list) > maxLen {
+		list = list[len(list)-maxLen:]
+	}
+	s.putLocked(key, memoryValue{isList: true, list: list}, 0, false)
+	return nil
+}
+
+func (s *memoryStore) ListRange(ctx context.Context, key string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, entry, ok := s.lookupLocked(key)
+	if !ok || !entry.value.isList {
+		return []string{}, nil
+	}
+	out := make([]string, len(entry.value.list))
+	copy(out, entry.value.list)
+	return out, nil
+}
+
+func (s *memoryStore) Keys(ctx context.Context, pattern string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	matches := make([]string, 0, len(s.entries))
+	for key := range s.entries {
+		if matchPattern(pattern, key) {
+			matches = append(matches, key)
+		}
+	}
+	return matches, nil
+}
+
+func (s *memoryStore) Del(ctx context.Context, keys ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, key := range keys {
+		if elem, ok := s.entries[key]; ok {
+			s.ll.Remove(elem)
+			delete(s.entries, key)
+		}
+	}
+	return nil
+}
+
+func (s *memoryStore) Publish(ctx context.Context, channel, message string) error {
+	s.subsMu.Lock()
+	subscribers := append([]chan string(nil), s.subs[channel]...)
+	s.subsMu.Unlock()
+
+	for _, ch := range subscribers {
+		select {
+		case ch <- message:
+		default:
+		}
+	}
+	return nil
+}
+
+func (s *memoryStore) Subscribe(ctx context.Context, channel string) (<-chan string, func(), error) {
+	ch := make(chan string, 16)
+
+	s.subsMu.Lock()
+	s.subs[channel] = append(s.subs[channel], ch)
+	s.subsMu.Unlock()
+
+	closeFn := func() {
+		s.subsMu.Lock()
+		defer s.subsMu.Unlock()
+		subscribers := s.subs[channel]
+		for i, sub := range subscribers {
+			if sub == ch {
+				s.subs[channel] = append(subscribers[:i], subscribers[i+1:]...)
+				break
+			}
+		}
+		close(ch)
+	}
+
+	return ch, closeFn, nil
+}
@@ -0,0 +1,142 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryStoreGetSetAndHash(t *testing.T) {
+	store := newMemoryStore(context.Background(), 1024)
+	ctx := context.Background()
+
+	if _, err := store.Get(ctx, "missing"); err != ErrNotFound {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	if err := store.Set(ctx, "foo", "bar", 0); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if got, err := store.Get(ctx, "foo"); err != nil || got != "bar" {
+		t.Fatalf("Get: got %q, err %v", got, err)
+	}
+
+	if err := store.HSet(ctx, "proxy:/x", map[string]string{"response": "body"}); err != nil {
+		t.Fatalf("HSet: %v", err)
+	}
+	fields, err := store.HGetAll(ctx, "proxy:/x")
+	if err != nil {
+		t.Fatalf("HGetAll: %v", err)
+	}
+	if fields["response"] != "body" {
+		t.Fatalf("unexpected fields: %v", fields)
+	}
+
+	keys, err := store.Keys(ctx, "proxy:*")
+	if err != nil || len(keys) != 1 {
+		t.Fatalf("Keys: got %v, err %v", keys, err)
+	}
+
+	if err := store.Del(ctx, "proxy:/x"); err != nil {
+		t.Fatalf("Del: %v", err)
+	}
+	if keys, _ := store.Keys(ctx, "proxy:*"); len(keys) != 0 {
+		t.Fatalf("expected no keys after Del, got %v", keys)
+	}
+}
+
+func TestMemoryStoreTTLExpiry(t *testing.T) {
+	store := newMemoryStore(context.Background(), 1024)
+	ctx := context.Background()
+
+	if err := store.Set(ctx, "foo", "bar", 10*time.Millisecond); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	if _, err := store.Get(ctx, "foo"); err != ErrNotFound {
+		t.Fatalf("expected expired key to be gone, got err %v", err)
+	}
+}
+
+func TestMemoryStoreEvictsLeastRecentlyUsed(t *testing.T) {
+	store := newMemoryStore(context.Background(), 2)
+	ctx := context.Background()
+
+	store.Set(ctx, "a", "1", 0)
+	store.Set(ctx, "b", "2", 0)
+	store.Get(ctx, "a") // touch "a" so "b" becomes the least recently used
+	store.Set(ctx, "c", "3", 0)
+
+	if _, err := store.Get(ctx, "b"); err != ErrNotFound {
+		t.Fatalf("expected 'b' to be evicted, got err %v", err)
+	}
+	if got, err := store.Get(ctx, "a"); err != nil || got != "1" {
+		t.Fatalf("expected 'a' to survive, got %q, err %v", got, err)
+	}
+	if got, err := store.Get(ctx, "c"); err != nil || got != "3" {
+		t.Fatalf("expected 'c' to survive, got %q, err %v", got, err)
+	}
+}
+
+func TestMemoryStoreSetNX(t *testing.T) {
+	store := newMemoryStore(context.Background(), 1024)
+	ctx := context.Background()
+
+	acquired, err := store.SetNX(ctx, "lock", "1", time.Second)
+	if err != nil || !acquired {
+		t.Fatalf("expected first SetNX to succeed, got %v, err %v", acquired, err)
+	}
+
+	acquired, err = store.SetNX(ctx, "lock", "2", time.Second)
+	if err != nil || acquired {
+		t.Fatalf("expected second SetNX to fail, got %v, err %v", acquired, err)
+	}
+}
+
+func TestMemoryStoreListPushTrimsToMaxLen(t *testing.T) {
+	store := newMemoryStore(context.Background(), 1024)
+	ctx := context.Background()
+
+	for i := 0; i < 5; i++ {
+		if err := store.ListPush(ctx, "samples", string(rune('a'+i)), 3); err != nil {
+			t.Fatalf("ListPush: %v", err)
+		}
+	}
+
+	got, err := store.ListRange(ctx, "samples")
+	if err != nil {
+		t.Fatalf("ListRange: %v", err)
+	}
+	want := []string{"c", "d", "e"}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+	}
+}
+
+func TestMemoryStorePublishSubscribe(t *testing.T) {
+	store := newMemoryStore(context.Background(), 1024)
+	ctx := context.Background()
+
+	msgs, closeFn, err := store.Subscribe(ctx, "chan")
+	if err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	defer closeFn()
+
+	store.Publish(ctx, "chan", "hello")
+
+	select {
+	case got := <-msgs:
+		if got != "hello" {
+			t.Fatalf("got %q, want %q", got, "hello")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for published message")
+	}
+}
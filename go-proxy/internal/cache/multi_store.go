@@ -0,0 +1,99 @@
+package cache
+
+import (
+	"context"
+	"strconv"
+	"time"
+)
+
+// multiStore is a two-tier Store: l1 is an in-process cache consulted first
+// (fast, but only warm for this instance), l2 is the shared backend that
+// every instance ultimately agrees on. Get and HGetAll repopulate l1 on an
+// l2 hit; Set, HSet, Incr and Del go to both tiers. Cross-instance
+// coordination (SetNX, Publish, Subscribe) only makes sense against shared
+// infrastructure, so those are forwarded to l2 alone, as are Keys,
+// ListPush and ListRange.
+type multiStore struct {
+	l1 *memoryStore
+	l2 Store
+}
+
+func newMultiStore(l1 *memoryStore, l2 Store) *multiStore {
+	return &multiStore{l1: l1, l2: l2}
+}
+
+func (s *multiStore) Get(ctx context.Context, key string) (string, error) {
+	if val, err := s.l1.Get(ctx, key); err == nil {
+		return val, nil
+	}
+
+	val, err := s.l2.Get(ctx, key)
+	if err != nil {
+		return "", err
+	}
+	s.l1.Set(ctx, key, val, 0)
+	return val, nil
+}
+
+func (s *multiStore) Set(ctx context.Context, key, value string, ttl time.Duration) error {
+	s.l1.Set(ctx, key, value, ttl)
+	return s.l2.Set(ctx, key, value, ttl)
+}
+
+func (s *multiStore) SetNX(ctx context.Context, key, value string, ttl time.Duration) (bool, error) {
+	return s.l2.SetNX(ctx, key, value, ttl)
+}
+
+func (s *multiStore) HGetAll(ctx context.Context, key string) (map[string]string, error) {
+	if fields, err := s.l1.HGetAll(ctx, key); err == nil && len(fields) > 0 {
+		return fields, nil
+	}
+
+	fields, err := s.l2.HGetAll(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) > 0 {
+		s.l1.HSet(ctx, key, fields)
+	}
+	return fields, nil
+}
+
+func (s *multiStore) HSet(ctx context.Context, key string, fields map[string]string) error {
+	s.l1.HSet(ctx, key, fields)
+	return s.l2.HSet(ctx, key, fields)
+}
+
+func (s *multiStore) Incr(ctx context.Context, key string) (int64, error) {
+	count, err := s.l2.Incr(ctx, key)
+	if err != nil {
+		return 0, err
+	}
+	s.l1.Set(ctx, key, strconv.FormatInt(count, 10), 0)
+	return count, nil
+}
+
+func (s *multiStore) Keys(ctx context.Context, pattern string) ([]string, error) {
+	return s.l2.Keys(ctx, pattern)
+}
+
+func (s *multiStore) Del(ctx context.Context, keys ...string) error {
+	s.l1.Del(ctx, keys...)
+	return s.l2.Del(ctx, keys...)
+}
+
+func (s *multiStore) ListPush(ctx context.Context, key, value string, maxLen int) error {
+	return s.l2.ListPush(ctx, key, value, maxLen)
+}
+
+func (s *multiStore) ListRange(ctx context.Context, key string) ([]string, error) {
+	return s.l2.ListRange(ctx, key)
+}
+
+func (s *multiStore) Publish(ctx context.Context, channel, message string) error {
+	return s.l2.Publish(ctx, channel, message)
+}
+
+func (s *multiStore) Subscribe(ctx context.Context, channel string) (<-chan string, func(), error) {
+	return s.l2.Subscribe(ctx, channel)
+}
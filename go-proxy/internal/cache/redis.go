@@ -0,0 +1,33 @@
+package cache
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// RedisClient is the shared cache Store used across the proxy.
+var RedisClient Store
+
+var ctx = context.Background()
+
+// InitRedis initializes the global Store for the given Redis address and
+// backend selector (see buildStore), using rueidis with client-side caching
+// enabled for localCacheTTL when the backend is redis:// or unset (multi://
+// uses go-redis for its Redis tier). It falls back
+// to a plain go-redis-backed Store if a rueidis connection can't be
+// established, and to the plain Redis backend if backendURI is invalid.
+func InitRedis(addr string, localCacheTTL time.Duration, backendURI string) {
+	store, err := buildStore(backendURI, addr, localCacheTTL)
+	if err != nil {
+		log.Printf("cache: %v; falling back to go-redis", err)
+		RedisClient = newGoredisStore(addr)
+		return
+	}
+	RedisClient = store
+}
+
+// GetContext returns the background context used for Redis operations.
+func GetContext() context.Context {
+	return ctx
+}
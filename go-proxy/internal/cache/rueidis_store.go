@@ -0,0 +1,143 @@
+package cache
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/rueidis"
+)
+
+// rueidisStore is the production Store backend. It uses rueidis's
+// client-side caching (RESP3 CLIENT TRACKING) so repeated HGetAll lookups on
+// the same "proxy:*" key are served from an in-process cache that Redis
+// invalidates automatically when the key changes.
+type rueidisStore struct {
+	client        rueidis.Client
+	localCacheTTL time.Duration
+}
+
+func newRueidisStore(addr string, localCacheTTL time.Duration) (*rueidisStore, error) {
+	client, err := rueidis.NewClient(rueidis.ClientOption{InitAddress: []string{addr}})
+	if err != nil {
+		return nil, err
+	}
+	return &rueidisStore{client: client, localCacheTTL: localCacheTTL}, nil
+}
+
+func (s *rueidisStore) Get(ctx context.Context, key string) (string, error) {
+	val, err := s.client.Do(ctx, s.client.B().Get().Key(key).Build()).ToString()
+	if rueidis.IsRedisNil(err) {
+		return "", ErrNotFound
+	}
+	return val, err
+}
+
+func (s *rueidisStore) Set(ctx context.Context, key, value string, ttl time.Duration) error {
+	builder := s.client.B().Set().Key(key).Value(value)
+	if ttl > 0 {
+		return s.client.Do(ctx, builder.ExSeconds(int64(ttl.Seconds())).Build()).Error()
+	}
+	return s.client.Do(ctx, builder.Build()).Error()
+}
+
+func (s *rueidisStore) SetNX(ctx context.Context, key, value string, ttl time.Duration) (bool, error) {
+	builder := s.client.B().Set().Key(key).Value(value).Nx()
+	var cmd rueidis.Completed
+	if ttl > 0 {
+		cmd = builder.ExSeconds(int64(ttl.Seconds())).Build()
+	} else {
+		cmd = builder.Build()
+	}
+
+	err := s.client.Do(ctx, cmd).Error()
+	if rueidis.IsRedisNil(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// HGetAll is the hot path: it is served through DoCache so concurrent
+// lookups of the same key are answered from the local, server-invalidated
+// cache instead of round-tripping to Redis.
+func (s *rueidisStore) HGetAll(ctx context.Context, key string) (map[string]string, error) {
+	resp := s.client.DoCache(ctx, s.client.B().Hgetall().Key(key).Cache(), s.localCacheTTL)
+	raw, err := resp.ToMap()
+	if rueidis.IsRedisNil(err) {
+		return map[string]string{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	fields := make(map[string]string, len(raw))
+	for k, v := range raw {
+		str, err := v.ToString()
+		if err != nil {
+			return nil, err
+		}
+		fields[k] = str
+	}
+	return fields, nil
+}
+
+func (s *rueidisStore) HSet(ctx context.Context, key string, fields map[string]string) error {
+	if len(fields) == 0 {
+		return nil
+	}
+
+	cmd := s.client.B().Hset().Key(key).FieldValue()
+	for field, value := range fields {
+		cmd = cmd.FieldValue(field, value)
+	}
+	return s.client.Do(ctx, cmd.Build()).Error()
+}
+
+func (s *rueidisStore) Incr(ctx context.Context, key string) (int64, error) {
+	return s.client.Do(ctx, s.client.B().Incr().Key(key).Build()).ToInt64()
+}
+
+func (s *rueidisStore) Keys(ctx context.Context, pattern string) ([]string, error) {
+	return s.client.Do(ctx, s.client.B().Keys().Pattern(pattern).Build()).AsStrSlice()
+}
+
+func (s *rueidisStore) Del(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	return s.client.Do(ctx, s.client.B().Del().Key(keys...).Build()).Error()
+}
+
+func (s *rueidisStore) ListPush(ctx context.Context, key, value string, maxLen int) error {
+	if err := s.client.Do(ctx, s.client.B().Rpush().Key(key).Element(value).Build()).Error(); err != nil {
+		return err
+	}
+	return s.client.Do(ctx, s.client.B().Ltrim().Key(key).Start(int64(-maxLen)).Stop(-1).Build()).Error()
+}
+
+func (s *rueidisStore) ListRange(ctx context.Context, key string) ([]string, error) {
+	return s.client.Do(ctx, s.client.B().Lrange().Key(key).Start(0).Stop(-1).Build()).AsStrSlice()
+}
+
+func (s *rueidisStore) Publish(ctx context.Context, channel, message string) error {
+	return s.client.Do(ctx, s.client.B().Publish().Channel(channel).Message(message).Build()).Error()
+}
+
+func (s *rueidisStore) Subscribe(ctx context.Context, channel string) (<-chan string, func(), error) {
+	subCtx, cancel := context.WithCancel(ctx)
+	out := make(chan string)
+
+	go func() {
+		defer close(out)
+		_ = s.client.Receive(subCtx, s.client.B().Subscribe().Channel(channel).Build(), func(msg rueidis.PubSubMessage) {
+			select {
+			case out <- msg.Message:
+			case <-subCtx.Done():
+			}
+		})
+	}()
+
+	return out, cancel, nil
+}
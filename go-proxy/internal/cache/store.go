@@ -0,0 +1,38 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrNotFound is returned by Get when the key does not exist.
+var ErrNotFound = errors.New("cache: key not found")
+
+// Store is the set of Redis operations the proxy depends on. It exists so
+// the hot path (HGetAll on "proxy:*" keys) can be served by a client-side
+// caching backend while tests swap in a lightweight fake.
+type Store interface {
+	Get(ctx context.Context, key string) (string, error)
+	Set(ctx context.Context, key, value string, ttl time.Duration) error
+	// SetNX sets key to value only if it does not already exist, returning
+	// whether the set happened. Used for leader election, e.g. request
+	// coalescing's in-flight marker.
+	SetNX(ctx context.Context, key, value string, ttl time.Duration) (bool, error)
+	HGetAll(ctx context.Context, key string) (map[string]string, error)
+	HSet(ctx context.Context, key string, fields map[string]string) error
+	Incr(ctx context.Context, key string) (int64, error)
+	Keys(ctx context.Context, pattern string) ([]string, error)
+	Del(ctx context.Context, keys ...string) error
+	// ListPush appends value to the list at key and trims it to maxLen,
+	// keeping only the most recent entries. Used for rolling windows such as
+	// the response-time samples behind stats:response_times.
+	ListPush(ctx context.Context, key, value string, maxLen int) error
+	// ListRange returns every element currently stored in the list at key.
+	ListRange(ctx context.Context, key string) ([]string, error)
+	Publish(ctx context.Context, channel, message string) error
+	// Subscribe returns a channel of message payloads for channel and a
+	// close function that must be called to release the subscription. The
+	// returned channel is closed once the subscription ends.
+	Subscribe(ctx context.Context, channel string) (msgs <-chan string, closeFn func(), err error)
+}
@@ -0,0 +1,245 @@
+package cache
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"time"
+)
+
+// SyncChannel is the Pub/Sub channel instances use to exchange cache-sync
+// messages, mirroring blocky's blocky_sync channel.
+const SyncChannel = "ai-cache-proxy:sync"
+
+// Message types exchanged over SyncChannel.
+const (
+	syncCachePut        = "cache-put"
+	syncCacheInvalidate = "cache-invalidate"
+	syncStatsReset      = "stats-reset"
+	syncAIToggle        = "ai-toggle"
+)
+
+// aiEnabledKey is the same Store key handleAIToggle reads/writes directly;
+// apply() must use it too so a remote ai-toggle is visible through Get.
+const aiEnabledKey = "ai:enabled"
+
+// syncMessage is the wire format published on SyncChannel.
+type syncMessage struct {
+	Type    string            `json:"type"`
+	Key     string            `json:"key,omitempty"`
+	Pattern string            `json:"pattern,omitempty"` // cache-invalidate, for logging only
+	Keys    []string          `json:"keys,omitempty"`    // cache-invalidate, the keys to actually delete
+	Fields  map[string]string `json:"fields,omitempty"`  // cache-put hash fields
+	Payload string            `json:"payload,omitempty"`
+	TTL     int64             `json:"ttl,omitempty"` // seconds, 0 means no expiry
+	Origin  string            `json:"origin"`
+}
+
+// Client keeps this instance's Store in sync with the other ai-cache-proxy
+// instances sharing the same Redis deployment. It publishes a message on
+// SyncChannel whenever this instance mutates cache state, and applies
+// messages published by other instances straight through to its own store
+// (in particular its L1 tier, which nothing else invalidates for it),
+// ignoring messages that originated from itself to avoid feedback loops.
+type Client struct {
+	store    Store
+	clientID string
+	enabled  bool
+}
+
+// NewClient creates a sync Client bound to store. If enabled is false, Start
+// and the Publish* calls are no-ops: nothing is published or applied.
+func NewClient(store Store, enabled bool) *Client {
+	return &Client{
+		store:    store,
+		clientID: newClientID(),
+		enabled:  enabled,
+	}
+}
+
+// ClientID returns the stable ID this instance uses as message origin.
+func (c *Client) ClientID() string {
+	return c.clientID
+}
+
+// AIEnabled reports the AI-toggle state, read through the store so it
+// reflects both local toggles and ones applied from remote sync messages.
+func (c *Client) AIEnabled() bool {
+	v, err := c.store.Get(context.Background(), aiEnabledKey)
+	if err != nil {
+		return true // handleAIToggle defaults to enabled when the key is unset
+	}
+	return v != "false"
+}
+
+// Start subscribes to SyncChannel and applies remote messages until ctx is
+// canceled, reconnecting automatically on Redis dropouts.
+func (c *Client) Start(ctx context.Context) {
+	if !c.enabled {
+		return
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+
+		c.listen(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Second):
+			log.Printf("cache: sync subscription dropped, reconnecting")
+		}
+	}
+}
+
+func (c *Client) listen(ctx context.Context) {
+	msgs, closeFn, err := c.store.Subscribe(ctx, SyncChannel)
+	if err != nil {
+		log.Printf("cache: sync subscribe failed: %v", err)
+		return
+	}
+	defer closeFn()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case payload, ok := <-msgs:
+			if !ok {
+				return
+			}
+			c.applyRaw(ctx, payload)
+		}
+	}
+}
+
+func (c *Client) applyRaw(ctx context.Context, payload string) {
+	var msg syncMessage
+	if err := json.Unmarshal([]byte(payload), &msg); err != nil {
+		log.Printf("cache: dropping malformed sync message: %v", err)
+		return
+	}
+
+	if msg.Origin == c.clientID {
+		return
+	}
+
+	c.apply(ctx, msg)
+}
+
+// apply writes a remote message through to c.store, so this instance's own
+// Store (and in particular its L1 tier under CACHE_BACKEND=multi://) agrees
+// with whatever the originating instance just did.
+func (c *Client) apply(ctx context.Context, msg syncMessage) {
+	switch msg.Type {
+	case syncCachePut:
+		if err := c.store.HSet(ctx, msg.Key, msg.Fields); err != nil {
+			log.Printf("cache: sync apply cache-put for %s: %v", msg.Key, err)
+		}
+	case syncCacheInvalidate:
+		// msg.Keys is the concrete key list the origin resolved (and already
+		// deleted) before broadcasting. Re-resolving msg.Pattern against our
+		// own store here would race with that deletion already having
+		// happened on the shared L2 tier, and find nothing to delete from
+		// our own L1.
+		if len(msg.Keys) == 0 {
+			return
+		}
+		if err := c.store.Del(ctx, msg.Keys...); err != nil {
+			log.Printf("cache: sync apply cache-invalidate (pattern %q): %v", msg.Pattern, err)
+		}
+	case syncStatsReset:
+		// Stats live in the shared store; nothing to apply locally beyond
+		// acknowledging the reset happened elsewhere.
+	case syncAIToggle:
+		if err := c.store.Set(ctx, aiEnabledKey, msg.Payload, 0); err != nil {
+			log.Printf("cache: sync apply ai-toggle: %v", err)
+		}
+	default:
+		log.Printf("cache: ignoring unknown sync message type %q", msg.Type)
+	}
+}
+
+// PublishCachePut broadcasts the hash fields a caller already wrote to the
+// local store (via storeCacheEntry) so other instances' apply() can write
+// them through to their own store in turn.
+func (c *Client) PublishCachePut(ctx context.Context, key string, fields map[string]string, ttl time.Duration) error {
+	return c.publish(ctx, syncMessage{
+		Type:   syncCachePut,
+		Key:    key,
+		Fields: fields,
+		TTL:    int64(ttl / time.Second),
+		Origin: c.clientID,
+	})
+}
+
+// PublishCacheInvalidate broadcasts the deletion of keys, which the caller
+// has already resolved (e.g. via Store.Keys) and deleted from its own
+// store. pattern is carried only for logging on the receiving end.
+func (c *Client) PublishCacheInvalidate(ctx context.Context, pattern string, keys []string) error {
+	return c.publish(ctx, syncMessage{
+		Type:    syncCacheInvalidate,
+		Pattern: pattern,
+		Keys:    keys,
+		Origin:  c.clientID,
+	})
+}
+
+// PublishStatsReset broadcasts that this instance reset its stats counters.
+func (c *Client) PublishStatsReset(ctx context.Context) error {
+	return c.publish(ctx, syncMessage{Type: syncStatsReset, Origin: c.clientID})
+}
+
+// PublishAIToggle broadcasts an ai-toggle a caller already applied to the
+// local store.
+func (c *Client) PublishAIToggle(ctx context.Context, enabled bool) error {
+	payload := "false"
+	if enabled {
+		payload = "true"
+	}
+
+	return c.publish(ctx, syncMessage{
+		Type:    syncAIToggle,
+		Payload: payload,
+		Origin:  c.clientID,
+	})
+}
+
+func (c *Client) publish(ctx context.Context, msg syncMessage) error {
+	if !c.enabled {
+		return nil
+	}
+
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	return c.store.Publish(ctx, SyncChannel, string(body))
+}
+
+func newClientID() string {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(buf)
+}
+
+// matchPattern reports whether key matches a Redis-style glob pattern. Only
+// the trailing "*" wildcard used by cache invalidation (e.g. "proxy:*") is
+// supported.
+func matchPattern(pattern, key string) bool {
+	if idx := len(pattern) - 1; idx >= 0 && pattern[idx] == '*' {
+		prefix := pattern[:idx]
+		return len(key) >= len(prefix) && key[:len(prefix)] == prefix
+	}
+	return pattern == key
+}
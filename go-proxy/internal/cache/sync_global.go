@@ -0,0 +1,65 @@
+package cache
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// Sync is the process-wide sync Client, set up by InitSync. It is nil until
+// InitSync runs and holds a disabled Client when sync is turned off, so
+// callers should use the package-level Publish* helpers below rather than
+// touching Sync directly.
+var Sync *Client
+
+// InitSync wires up the global sync Client against RedisClient and, if
+// enabled, starts listening for remote updates in the background.
+func InitSync(enabled bool) {
+	Sync = NewClient(RedisClient, enabled)
+	if enabled {
+		go Sync.Start(context.Background())
+	}
+}
+
+// PublishCachePut broadcasts a cache write if sync is enabled; it is a no-op
+// otherwise.
+func PublishCachePut(key string, fields map[string]string, ttl time.Duration) {
+	if Sync == nil || !Sync.enabled {
+		return
+	}
+	if err := Sync.PublishCachePut(GetContext(), key, fields, ttl); err != nil {
+		log.Printf("cache: failed to publish cache-put sync message: %v", err)
+	}
+}
+
+// PublishCacheInvalidate broadcasts the deletion of keys if sync is
+// enabled. pattern is the glob the caller resolved keys from, kept only for
+// the receiving end's logs.
+func PublishCacheInvalidate(pattern string, keys []string) {
+	if Sync == nil || !Sync.enabled {
+		return
+	}
+	if err := Sync.PublishCacheInvalidate(GetContext(), pattern, keys); err != nil {
+		log.Printf("cache: failed to publish cache-invalidate sync message: %v", err)
+	}
+}
+
+// PublishStatsReset broadcasts a stats reset if sync is enabled.
+func PublishStatsReset() {
+	if Sync == nil || !Sync.enabled {
+		return
+	}
+	if err := Sync.PublishStatsReset(GetContext()); err != nil {
+		log.Printf("cache: failed to publish stats-reset sync message: %v", err)
+	}
+}
+
+// PublishAIToggle broadcasts an AI-toggle change if sync is enabled.
+func PublishAIToggle(enabled bool) {
+	if Sync == nil || !Sync.enabled {
+		return
+	}
+	if err := Sync.PublishAIToggle(GetContext(), enabled); err != nil {
+		log.Printf("cache: failed to publish ai-toggle sync message: %v", err)
+	}
+}
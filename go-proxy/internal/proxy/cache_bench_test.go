@@ -0,0 +1,85 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"go-proxy/internal/cache"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+// redisBenchAddrEnv names the real Redis server BenchmarkHGetAllRueidis needs.
+// rueidis's client-side caching requires RESP3 CLIENT TRACKING, which
+// miniredis (the fake Redis the rest of this suite runs against) doesn't
+// support, so this benchmark can't use it the way its go-redis/memory
+// siblings do.
+const redisBenchAddrEnv = "REDIS_BENCH_ADDR"
+
+// BenchmarkHGetAllGoRedis and BenchmarkHGetAllRueidis compare the hot cache
+// lookup path (HGetAll on a "proxy:*" key) between the go-redis backend and
+// the rueidis backend with client-side caching enabled. Run with:
+//
+//	REDIS_BENCH_ADDR=localhost:6379 go test ./internal/proxy/... -bench HGetAll -benchmem
+//
+// then diff the two with benchstat to see the client-side cache win.
+func BenchmarkHGetAllGoRedis(b *testing.B) {
+	benchmarkHGetAll(b, func(addr string) cache.Store {
+		return cache.NewGoRedisStore(addr)
+	})
+}
+
+func BenchmarkHGetAllRueidis(b *testing.B) {
+	addr := os.Getenv(redisBenchAddrEnv)
+	if addr == "" {
+		b.Skipf("skipping: rueidis requires RESP3 CLIENT TRACKING, which miniredis doesn't support; set %s to a real Redis address to run this benchmark", redisBenchAddrEnv)
+	}
+
+	ctx := context.Background()
+	key := "proxy:/bench"
+	store, err := cache.NewRueidisStore(addr, 600*time.Second)
+	if err != nil {
+		b.Fatalf("NewRueidisStore: %v", err)
+	}
+	if err := store.HSet(ctx, key, map[string]string{"response": fmt.Sprintf("%1000d", 0)}); err != nil {
+		b.Fatalf("HSet: %v", err)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := store.HGetAll(ctx, key); err != nil {
+			b.Fatalf("HGetAll: %v", err)
+		}
+	}
+}
+
+func BenchmarkHGetAllMemory(b *testing.B) {
+	benchmarkHGetAll(b, func(addr string) cache.Store {
+		return cache.NewMemoryStore(1024)
+	})
+}
+
+func benchmarkHGetAll(b *testing.B, newStore func(addr string) cache.Store) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		b.Fatalf("failed to start miniredis: %v", err)
+	}
+	defer mr.Close()
+
+	store := newStore(mr.Addr())
+	ctx := context.Background()
+	key := "proxy:/bench"
+	if err := store.HSet(ctx, key, map[string]string{"response": fmt.Sprintf("%1000d", 0)}); err != nil {
+		b.Fatalf("HSet: %v", err)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := store.HGetAll(ctx, key); err != nil {
+			b.Fatalf("HGetAll: %v", err)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package proxy
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"go-proxy/internal/cache"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// fetchGroup coalesces concurrent upstream fetches for the same cache key
+// within this process.
+var fetchGroup singleflight.Group
+
+// inflightTTL bounds how long a leader marker (and thus a follower's wait)
+// can last before another instance is allowed to take over the fetch.
+const inflightTTL = 10 * time.Second
+
+// fetchUpstreamCoalesced ensures at most one upstream fetch happens per
+// cacheKey at a time, both within this process (via singleflight) and across
+// ai-cache-proxy instances sharing Redis (via a SET NX marker and a per-key
+// Pub/Sub channel that followers wait on).
+func fetchUpstreamCoalesced(cacheKey string, r *http.Request) (*upstreamResponse, error) {
+	v, err, _ := fetchGroup.Do(cacheKey, func() (interface{}, error) {
+		return fetchUpstreamAsClusterLeaderOrFollower(cacheKey, r)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.(*upstreamResponse), nil
+}
+
+// coalesceKeyFor derives the key fetchUpstreamCoalesced groups concurrent
+// fetches under. Once a resource's Vary headers are known, cacheKey already
+// reflects them and two requests sharing it are guaranteed to want the same
+// response. Before that (varyKnown false - no request for this resource has
+// come back from upstream yet), cacheKey is just the base, Vary-less key, so
+// folding it alone would let two concurrent requests that differ in a header
+// the eventual Vary ends up covering collapse into one fetch and both be
+// served whichever one happened to win the race. Folding a fingerprint of
+// the request's content-negotiation headers into the key instead means only
+// requests agreeing on those coalesce until Vary is known, trading away
+// coalescing we can't yet prove is safe for correctness.
+func coalesceKeyFor(cacheKey string, varyKnown bool, r *http.Request) string {
+	if varyKnown {
+		return cacheKey
+	}
+	return cacheKey + ":" + requestHeaderFingerprint(r)
+}
+
+// varyProneHeaders lists the request headers that commonly drive server-side
+// content negotiation, and so commonly end up in a response's Vary header.
+// requestHeaderFingerprint only folds these in, rather than every header on
+// the request, so a header that's unique per request for unrelated reasons
+// (a correlation ID, a session cookie) doesn't defeat coalescing for every
+// single request the way fingerprinting the whole header set would.
+var varyProneHeaders = []string{"Accept", "Accept-Charset", "Accept-Encoding", "Accept-Language", "Origin"}
+
+func requestHeaderFingerprint(r *http.Request) string {
+	h := sha256.New()
+	for _, name := range varyProneHeaders {
+		values := r.Header.Values(name)
+		if len(values) == 0 {
+			continue
+		}
+		h.Write([]byte(name))
+		h.Write([]byte{0})
+		for _, v := range values {
+			h.Write([]byte(v))
+			h.Write([]byte{0})
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+func fetchUpstreamAsClusterLeaderOrFollower(cacheKey string, r *http.Request) (*upstreamResponse, error) {
+	inflightKey := "inflight:" + cacheKey
+
+	acquired, err := cache.RedisClient.SetNX(cache.GetContext(), inflightKey, "1", inflightTTL)
+	if err != nil || acquired {
+		// We couldn't tell whether anyone else is fetching (Redis error) or
+		// we won the race: either way, fetch ourselves.
+		resp, fetchErr := fetchUpstream(r)
+		if acquired {
+			publishInflightResult(cacheKey, resp, fetchErr)
+			cache.RedisClient.Del(cache.GetContext(), inflightKey)
+		}
+		return resp, fetchErr
+	}
+
+	// Another instance is already fetching this key; wait for its result.
+	if resp, ok := waitForInflightResult(cacheKey, inflightTTL); ok {
+		return resp, nil
+	}
+
+	// The leader never published (timeout, crash, or dropped Pub/Sub):
+	// fall back to fetching it ourselves rather than failing the request.
+	return fetchUpstream(r)
+}
+
+func inflightChannel(cacheKey string) string {
+	return "ai-cache-proxy:inflight:" + cacheKey
+}
+
+// inflightResultKey holds the same payload published on inflightChannel, so
+// a follower that only starts subscribing after the leader has already
+// published can still pick up the result instead of waiting out the full
+// inflightTTL for a Pub/Sub message it will never see.
+func inflightResultKey(cacheKey string) string {
+	return "inflight:result:" + cacheKey
+}
+
+// inflightResultTTL bounds how long a published result stays readable via
+// inflightResultKey for a late-subscribing follower to catch up on.
+const inflightResultTTL = 5 * time.Second
+
+func publishInflightResult(cacheKey string, resp *upstreamResponse, fetchErr error) {
+	if fetchErr != nil {
+		return
+	}
+	payload, err := json.Marshal(resp)
+	if err != nil {
+		return
+	}
+
+	ctx := cache.GetContext()
+	// Written before the Publish so that a follower whose Subscribe call
+	// loses the race entirely (the leader finishes before it even starts
+	// listening) still has somewhere to find the result: it checks this key
+	// right after subscribing, covering the gap Pub/Sub itself can't.
+	cache.RedisClient.Set(ctx, inflightResultKey(cacheKey), string(payload), inflightResultTTL)
+	cache.RedisClient.Publish(ctx, inflightChannel(cacheKey), string(payload))
+}
+
+func waitForInflightResult(cacheKey string, timeout time.Duration) (*upstreamResponse, bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	msgs, closeFn, err := cache.RedisClient.Subscribe(ctx, inflightChannel(cacheKey))
+	if err != nil {
+		return nil, false
+	}
+	defer closeFn()
+
+	// The leader may have already published by the time our subscription
+	// attaches; it stores the result here first specifically so we can catch
+	// that instead of blocking on a Pub/Sub message that already went by.
+	if raw, err := cache.RedisClient.Get(ctx, inflightResultKey(cacheKey)); err == nil && raw != "" {
+		var resp upstreamResponse
+		if err := json.Unmarshal([]byte(raw), &resp); err == nil {
+			return &resp, true
+		}
+	}
+
+	select {
+	case payload, ok := <-msgs:
+		if !ok {
+			return nil, false
+		}
+		var resp upstreamResponse
+		if err := json.Unmarshal([]byte(payload), &resp); err != nil {
+			return nil, false
+		}
+		return &resp, true
+	case <-ctx.Done():
+		return nil, false
+	}
+}
@@ -0,0 +1,206 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go-proxy/internal/cache"
+)
+
+func TestFetchUpstreamCoalesced_SingleFlight(t *testing.T) {
+	setupTestCache(t)
+
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		time.Sleep(100 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("slow-response"))
+	}))
+	defer server.Close()
+
+	previous := upstreamTarget
+	upstreamTarget = server.URL
+	defer func() { upstreamTarget = previous }()
+
+	const concurrency = 100
+	var wg sync.WaitGroup
+	bodies := make([]string, concurrency)
+	errs := make([]error, concurrency)
+
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			req := httptest.NewRequest(http.MethodGet, "/slow", nil)
+			resp, err := fetchUpstreamCoalesced("proxy:GET:/slow", req)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			bodies[i] = string(resp.Body)
+		}(i)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("request %d failed: %v", i, err)
+		}
+	}
+	for i, body := range bodies {
+		if body != "slow-response" {
+			t.Fatalf("request %d got unexpected body %q", i, body)
+		}
+	}
+	if got := atomic.LoadInt32(&hits); got != 1 {
+		t.Fatalf("expected exactly one upstream call, got %d", got)
+	}
+}
+
+func TestCoalesceKeyFor(t *testing.T) {
+	reqA := httptest.NewRequest(http.MethodGet, "/widgets", nil)
+	reqA.Header.Set("Accept-Language", "en")
+	reqB := httptest.NewRequest(http.MethodGet, "/widgets", nil)
+	reqB.Header.Set("Accept-Language", "fr")
+
+	if got := coalesceKeyFor("proxy:GET:/widgets", true, reqA); got != "proxy:GET:/widgets" {
+		t.Fatalf("varyKnown should return cacheKey unchanged, got %q", got)
+	}
+
+	keyA := coalesceKeyFor("proxy:GET:/widgets", false, reqA)
+	keyB := coalesceKeyFor("proxy:GET:/widgets", false, reqB)
+	if keyA == keyB {
+		t.Fatal("requests with different header values must not share a coalesce key before Vary is known")
+	}
+
+	if got := coalesceKeyFor("proxy:GET:/widgets", false, reqA); got != keyA {
+		t.Fatal("coalesceKeyFor must be deterministic for identical requests")
+	}
+
+	reqC := httptest.NewRequest(http.MethodGet, "/widgets", nil)
+	reqC.Header.Set("X-Request-Id", "one")
+	reqD := httptest.NewRequest(http.MethodGet, "/widgets", nil)
+	reqD.Header.Set("X-Request-Id", "two")
+	if got, want := coalesceKeyFor("proxy:GET:/widgets", false, reqC), coalesceKeyFor("proxy:GET:/widgets", false, reqD); got != want {
+		t.Fatal("a per-request-unique header unrelated to content negotiation must not defeat coalescing")
+	}
+}
+
+// TestFetchUpstreamAsClusterLeaderOrFollower_CrossInstance exercises the
+// cross-instance SetNX/Pub-Sub path directly: fetchUpstreamCoalesced's own
+// singleflight.Group would collapse same-process concurrent calls for the
+// same cacheKey before the leader/follower logic ever ran, so it can never
+// reach this branch.
+func TestFetchUpstreamAsClusterLeaderOrFollower_CrossInstance(t *testing.T) {
+	setupTestCache(t)
+
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		time.Sleep(100 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("leader-response"))
+	}))
+	defer server.Close()
+
+	previous := upstreamTarget
+	upstreamTarget = server.URL
+	defer func() { upstreamTarget = previous }()
+
+	const followers = 10
+	var wg sync.WaitGroup
+	bodies := make([]string, followers+1)
+	errs := make([]error, followers+1)
+
+	run := func(i int) {
+		defer wg.Done()
+		req := httptest.NewRequest(http.MethodGet, "/slow", nil)
+		resp, err := fetchUpstreamAsClusterLeaderOrFollower("proxy:GET:/slow", req)
+		if err != nil {
+			errs[i] = err
+			return
+		}
+		bodies[i] = string(resp.Body)
+	}
+
+	wg.Add(1)
+	go run(0)
+	time.Sleep(20 * time.Millisecond) // let the leader win the SetNX race first
+
+	for i := 1; i <= followers; i++ {
+		wg.Add(1)
+		go run(i)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("request %d failed: %v", i, err)
+		}
+	}
+	for i, body := range bodies {
+		if body != "leader-response" {
+			t.Fatalf("request %d got unexpected body %q", i, body)
+		}
+	}
+	if got := atomic.LoadInt32(&hits); got != 1 {
+		t.Fatalf("expected exactly one upstream call, got %d", got)
+	}
+}
+
+// TestFetchUpstreamAsClusterLeaderOrFollower_LateSubscriber covers a fast
+// leader that has already published its result before a follower's
+// Subscribe call attaches, which would otherwise strand the follower
+// waiting out the full timeout for a Pub/Sub message it already missed.
+func TestFetchUpstreamAsClusterLeaderOrFollower_LateSubscriber(t *testing.T) {
+	setupTestCache(t)
+
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("fast-response"))
+	}))
+	defer server.Close()
+
+	previous := upstreamTarget
+	upstreamTarget = server.URL
+	defer func() { upstreamTarget = previous }()
+
+	cacheKey := "proxy:GET:/fast"
+	req := httptest.NewRequest(http.MethodGet, "/fast", nil)
+
+	// Acquire the leader marker ourselves and run the leader's fetch to
+	// completion (including the result publish) before the follower ever
+	// calls Subscribe, so the only way it can succeed is via the
+	// inflightResultKey fallback in waitForInflightResult.
+	acquired, err := cache.RedisClient.SetNX(cache.GetContext(), "inflight:"+cacheKey, "1", inflightTTL)
+	if err != nil || !acquired {
+		t.Fatalf("expected to acquire the leader marker, acquired=%v err=%v", acquired, err)
+	}
+	resp, fetchErr := fetchUpstream(req)
+	if fetchErr != nil {
+		t.Fatalf("leader fetch: %v", fetchErr)
+	}
+	publishInflightResult(cacheKey, resp, nil)
+
+	start := time.Now()
+	got, ok := waitForInflightResult(cacheKey, inflightTTL)
+	if !ok {
+		t.Fatal("expected the late subscriber to find the already-published result")
+	}
+	if string(got.Body) != "fast-response" {
+		t.Fatalf("unexpected body %q", got.Body)
+	}
+	if elapsed := time.Since(start); elapsed >= inflightTTL {
+		t.Fatalf("follower took %v, expected it to return well before the %v timeout", elapsed, inflightTTL)
+	}
+	if got := atomic.LoadInt32(&hits); got != 1 {
+		t.Fatalf("expected exactly one upstream call, got %d", got)
+	}
+}
@@ -0,0 +1,193 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"go-proxy/internal/cache"
+)
+
+// eventsChannel is the Redis Pub/Sub channel proxyEvents are published to so
+// that /events subscribers converge across every ai-cache-proxy instance.
+const eventsChannel = "ai-cache-proxy:events"
+
+const eventClientBufferSize = 32
+const statsSnapshotInterval = 15 * time.Second
+const heartbeatInterval = 15 * time.Second
+
+// proxyEvent is one line of the /events stream: either a completed-request
+// event or a periodic stats snapshot.
+type proxyEvent struct {
+	Type      string      `json:"type"` // "request" or "stats"
+	Method    string      `json:"method,omitempty"`
+	Path      string      `json:"path,omitempty"`
+	Result    string      `json:"result,omitempty"`
+	Status    int         `json:"status,omitempty"`
+	LatencyMs int64       `json:"latency_ms,omitempty"`
+	Stats     *CacheStats `json:"stats,omitempty"`
+	Timestamp string      `json:"timestamp"`
+}
+
+// eventHub fans out events published on eventsChannel to every locally
+// connected /events client. It subscribes to Redis lazily, when the first
+// client connects, and tears the subscription down once the last client
+// disconnects.
+type eventHub struct {
+	mu       sync.Mutex
+	clients  map[chan string]struct{}
+	cancelFn context.CancelFunc
+}
+
+var hub = &eventHub{clients: make(map[chan string]struct{})}
+
+func (h *eventHub) subscribe() chan string {
+	ch := make(chan string, eventClientBufferSize)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.clients) == 0 {
+		ctx, cancel := context.WithCancel(context.Background())
+		h.cancelFn = cancel
+		go h.listen(ctx)
+		go h.snapshotLoop(ctx)
+	}
+	h.clients[ch] = struct{}{}
+	return ch
+}
+
+// unsubscribe removes ch and, once the last client has disconnected, stops
+// the Redis subscription and snapshot loop.
+func (h *eventHub) unsubscribe(ch chan string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.clients[ch]; !ok {
+		return
+	}
+	delete(h.clients, ch)
+	close(ch)
+
+	if len(h.clients) == 0 && h.cancelFn != nil {
+		h.cancelFn()
+		h.cancelFn = nil
+	}
+}
+
+func (h *eventHub) broadcast(msg string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.clients {
+		select {
+		case ch <- msg:
+		default:
+			// Slow consumer: drop the message rather than block the hub.
+		}
+	}
+}
+
+func (h *eventHub) listen(ctx context.Context) {
+	msgs, closeFn, err := cache.RedisClient.Subscribe(ctx, eventsChannel)
+	if err != nil {
+		log.Printf("proxy: events hub failed to subscribe: %v", err)
+		return
+	}
+	defer closeFn()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-msgs:
+			if !ok {
+				return
+			}
+			h.broadcast(msg)
+		}
+	}
+}
+
+// snapshotLoop periodically publishes a stats snapshot so every connected
+// dashboard stays current even without new proxied traffic.
+func (h *eventHub) snapshotLoop(ctx context.Context) {
+	ticker := time.NewTicker(statsSnapshotInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			stats, err := getCacheStats()
+			if err != nil {
+				continue
+			}
+			publishEvent(proxyEvent{Type: "stats", Stats: stats, Timestamp: time.Now().Format(time.RFC3339)})
+		}
+	}
+}
+
+// publishProxyEvent publishes a request-completion event for every
+// connected /events client (on this instance and others) to receive.
+func publishProxyEvent(method, path, result string, status int, latency time.Duration) {
+	publishEvent(proxyEvent{
+		Type:      "request",
+		Method:    method,
+		Path:      path,
+		Result:    result,
+		Status:    status,
+		LatencyMs: latency.Milliseconds(),
+		Timestamp: time.Now().Format(time.RFC3339),
+	})
+}
+
+func publishEvent(event proxyEvent) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+	cache.RedisClient.Publish(context.Background(), eventsChannel, string(payload))
+}
+
+// handleEvents upgrades the connection to text/event-stream and streams
+// proxyEvents as they're published, with a heartbeat comment every 15s to
+// keep the connection alive through idle periods. It returns as soon as the
+// client disconnects (r.Context().Done()).
+func handleEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	msgs := hub.subscribe()
+	defer hub.unsubscribe(msgs)
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case msg, ok := <-msgs:
+			if !ok {
+				return
+			}
+			fmt.Fprintf(w, "data: %s\n\n", msg)
+			flusher.Flush()
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+			flusher.Flush()
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package proxy
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEventsStreamDeliversRequestEvents(t *testing.T) {
+	setupTestCache(t)
+	setupUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("ok"))
+	})
+
+	proxyServer := httptest.NewServer(http.HandlerFunc(ProxyHandler))
+	defer proxyServer.Close()
+
+	client := &http.Client{}
+	resp, err := client.Get(proxyServer.URL + "/events")
+	if err != nil {
+		t.Fatalf("GET /events: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type = %q, want text/event-stream", ct)
+	}
+
+	// Give the hub time to establish its Redis subscription before
+	// triggering the event we expect to observe.
+	time.Sleep(100 * time.Millisecond)
+
+	if _, err := client.Get(proxyServer.URL + "/events-trigger-path"); err != nil {
+		t.Fatalf("triggering proxied request: %v", err)
+	}
+
+	lines := make(chan string, 16)
+	go func() {
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+	}()
+
+	deadline := time.After(5 * time.Second)
+	for {
+		select {
+		case line := <-lines:
+			if strings.HasPrefix(line, "data: ") && strings.Contains(line, "/events-trigger-path") {
+				return
+			}
+		case <-deadline:
+			t.Fatal("timed out waiting for a request event on the stream")
+		}
+	}
+}
+
+func TestEventsStreamStopsOnClientDisconnect(t *testing.T) {
+	setupTestCache(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/events", nil)
+	cancelCtx, cancel := context.WithCancel(req.Context())
+	req = req.WithContext(cancelCtx)
+
+	w := httptest.NewRecorder()
+	done := make(chan struct{})
+	go func() {
+		handleEvents(w, req)
+		close(done)
+	}()
+
+	// Let handleEvents reach its select loop, then simulate a disconnect.
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("handleEvents did not return after client disconnect")
+	}
+}
@@ -5,10 +5,13 @@ import (
 	"encoding/json"
 	"fmt"
 	"go-proxy/internal/cache"
-	"io"
+	"log"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 // AnalyticsResponse represents the analytics data structure
@@ -26,6 +29,7 @@ type CacheStats struct {
 	CacheHitRate    float64 `json:"cache_hit_rate"`
 	AIPredictions   int64   `json:"ai_predictions"`
 	AvgResponseTime string  `json:"average_response_time"`
+	P95ResponseTime string  `json:"p95_response_time"`
 }
 
 // ProxyHandler handles all proxy requests
@@ -53,103 +57,170 @@ func ProxyHandler(w http.ResponseWriter, r *http.Request) {
 	case "/dashboard":
 		handleDashboard(w, r)
 		return
+	case "/metrics":
+		promhttp.Handler().ServeHTTP(w, r)
+		return
+	case "/events":
+		handleEvents(w, r)
+		return
 	}
 
 	// Original proxy logic
 	handleProxyRequest(w, r)
 }
 
-// handleProxyRequest contains the original proxy logic
+// handleProxyRequest serves r from cache when possible, following RFC 7234
+// cache semantics, and otherwise forwards it upstream.
 func handleProxyRequest(w http.ResponseWriter, r *http.Request) {
 	startTime := time.Now()
-	
-	// Cache key based on request path
-	cacheKey := fmt.Sprintf("proxy:%s", r.URL.Path)
-
-	// Check if response is in cache
-	cachedResponse, err := cache.RedisClient.HGetAll(cache.GetContext(), cacheKey).Result()
-	if err == nil && len(cachedResponse) > 0 {
-		// Increment cache hits
-		cache.RedisClient.Incr(context.Background(), "stats:cache_hits")
-		
-		// If found in cache, return cached response
-		w.Header().Set("Content-Type", "application/json")
-		w.Write([]byte(cachedResponse["response"]))
+	reqDirectives := parseRequestDirectives(r)
+
+	if !isCacheableMethod(r.Method) || reqDirectives.noStore {
+		proxyWithoutCache(w, r)
 		return
 	}
 
-	// Increment cache misses
-	cache.RedisClient.Incr(context.Background(), "stats:cache_misses")
-
-	// If not in cache, continue with request to server
-	targetURL := "http://httpbin.org" + r.URL.Path
+	baseKey := baseCacheKey(r)
+	varyIndexKey := baseKey + ":vary"
 
-	// Create new request to target server
-	req, err := http.NewRequest(r.Method, targetURL, r.Body)
-	if err != nil {
-		http.Error(w, "Error creating request", http.StatusInternalServerError)
-		return
+	var varyHeaders []string
+	varyKnown := false
+	if raw, err := cache.RedisClient.Get(cache.GetContext(), varyIndexKey); err == nil && raw != "" {
+		varyKnown = true
+		if raw != noVarySentinel {
+			varyHeaders = splitVaryHeader(raw)
+		}
 	}
+	cacheKey := variantKey(baseKey, r, varyHeaders)
 
-	// Copy headers from original request
-	for name, values := range r.Header {
-		for _, value := range values {
-			req.Header.Add(name, value)
+	fields, _ := cache.RedisClient.HGetAll(cache.GetContext(), cacheKey)
+	entry, found := entryFromFields(fields)
+
+	if found {
+		now := time.Now()
+		fresh := computeFreshness(entry.Header, entry.StoredAt, now)
+		withinRequestedAge := !reqDirectives.hasMaxAge || fresh.age <= reqDirectives.maxAge
+
+		if !reqDirectives.noCache && withinRequestedAge && fresh.isFresh(reqDirectives.minFresh) {
+			cache.RedisClient.Incr(context.Background(), "stats:cache_hits")
+			recordRequest("hit", r.Method, r.URL.Path, entry.Status, startTime)
+			writeCacheEntry(w, entry, "HIT", fresh.age)
+			return
+		}
+
+		if entry.Header.Get("ETag") != "" || entry.Header.Get("Last-Modified") != "" {
+			if revalidated, ok := revalidateUpstream(entry, r); ok {
+				cache.RedisClient.Incr(context.Background(), "stats:cache_hits")
+				updated := &cachedEntry{Status: revalidated.Status, Header: revalidated.Header, Body: revalidated.Body, StoredAt: now}
+				fields := storeCacheEntry(cacheKey, updated, r, time.Since(startTime))
+				cache.PublishCachePut(cacheKey, fields, 0)
+				recordRequest("hit", r.Method, r.URL.Path, updated.Status, startTime)
+				writeCacheEntry(w, updated, "REVALIDATED", 0)
+				return
+			}
+
+			if parseResponseCacheControl(entry.Header).mustRevalidate {
+				recordRequest("error", r.Method, r.URL.Path, http.StatusGatewayTimeout, startTime)
+				http.Error(w, "Error revalidating cached response", http.StatusGatewayTimeout)
+				return
+			}
+
+			// Revalidation failed and must-revalidate wasn't set: serve the
+			// stale copy rather than fail the request outright.
+			cache.RedisClient.Incr(context.Background(), "stats:cache_hits")
+			recordRequest("hit", r.Method, r.URL.Path, entry.Status, startTime)
+			writeCacheEntry(w, entry, "HIT", fresh.age)
+			return
 		}
 	}
 
-	// Execute request to target server
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		http.Error(w, "Error sending request to target server", http.StatusInternalServerError)
+	if reqDirectives.onlyIfCached {
+		recordRequest("error", r.Method, r.URL.Path, http.StatusGatewayTimeout, startTime)
+		http.Error(w, "Requested resource is not cached", http.StatusGatewayTimeout)
 		return
 	}
-	defer resp.Body.Close()
 
-	// Read response from target server
-	body, err := io.ReadAll(resp.Body)
+	cache.RedisClient.Incr(context.Background(), "stats:cache_misses")
+
+	resp, err := fetchUpstreamCoalesced(coalesceKeyFor(cacheKey, varyKnown, r), r)
 	if err != nil {
-		http.Error(w, "Error reading response", http.StatusInternalServerError)
+		recordRequest("error", r.Method, r.URL.Path, http.StatusInternalServerError, startTime)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	// Prepare headers for storage
-	headers := make(map[string]string)
-	for name, values := range r.Header {
-		headers[name] = values[0] // Store only first header
+	if isStorable(r.Method, resp.Status, resp.Header) {
+		vary := resp.Header.Get("Vary")
+		cache.RedisClient.Set(cache.GetContext(), varyIndexKey, encodeVaryIndex(vary), 0)
+		if vary != "" {
+			cacheKey = variantKey(baseKey, r, splitVaryHeader(vary))
+		}
+		newEntry := &cachedEntry{Status: resp.Status, Header: resp.Header, Body: resp.Body, StoredAt: time.Now()}
+		fields := storeCacheEntry(cacheKey, newEntry, r, time.Since(startTime))
+		cache.PublishCachePut(cacheKey, fields, 0)
 	}
-	headersJSON, _ := json.Marshal(headers)
-
-	// Calculate response time
-	responseTime := time.Since(startTime)
-
-	// Store complete request and response data in Redis
-	purpose := "empty"
-	err = cache.RedisClient.HSet(context.Background(), cacheKey,
-		"request_method", r.Method,
-		"request_url", r.URL.String(),
-		"request_headers", string(headersJSON),
-		"response", string(body),
-		"purpose", purpose,
-		"response_time", responseTime.String(),
-		"timestamp", time.Now().Format(time.RFC3339)).Err()
+
+	cache.RedisClient.Incr(context.Background(), "stats:total_requests")
+	recordRequest("miss", r.Method, r.URL.Path, resp.Status, startTime)
+	writeUpstreamResponse(w, resp, "MISS")
+}
+
+// proxyWithoutCache forwards r upstream without consulting or populating the
+// cache, for non-idempotent methods and request-side no-store.
+func proxyWithoutCache(w http.ResponseWriter, r *http.Request) {
+	startTime := time.Now()
+	cache.RedisClient.Incr(context.Background(), "stats:cache_misses")
+	cache.RedisClient.Incr(context.Background(), "stats:total_requests")
+
+	resp, err := fetchUpstream(r)
 	if err != nil {
-		http.Error(w, "Error saving data to Redis", http.StatusInternalServerError)
+		recordRequest("error", r.Method, r.URL.Path, http.StatusInternalServerError, startTime)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	recordRequest("miss", r.Method, r.URL.Path, resp.Status, startTime)
+	writeUpstreamResponse(w, resp, "MISS")
+}
 
-	// Increment total requests
-	cache.RedisClient.Incr(context.Background(), "stats:total_requests")
+// storeCacheEntry writes entry to the cache and returns the hash fields it
+// wrote, so callers can hand the same fields to cache.PublishCachePut.
+func storeCacheEntry(cacheKey string, entry *cachedEntry, r *http.Request, responseTime time.Duration) map[string]string {
+	requestHeaders := make(map[string]string, len(r.Header))
+	for name, values := range r.Header {
+		requestHeaders[name] = values[0] // Store only first header
+	}
+	requestHeadersJSON, _ := json.Marshal(requestHeaders)
+
+	fields := entry.toFields(r.Method, r.URL.String(), string(requestHeadersJSON), responseTime)
+	if err := cache.RedisClient.HSet(context.Background(), cacheKey, fields); err != nil {
+		log.Printf("proxy: failed to store cache entry for %s: %v", cacheKey, err)
+	}
+	return fields
+}
+
+// writeCacheEntry serves a cached response, stamping it with X-Cache and Age.
+func writeCacheEntry(w http.ResponseWriter, entry *cachedEntry, cacheResult string, age time.Duration) {
+	copyHeader(w.Header(), entry.Header)
+	w.Header().Set("X-Cache", cacheResult)
+	w.Header().Set("Age", strconv.FormatInt(int64(age.Seconds()), 10))
+	w.WriteHeader(entry.Status)
+	w.Write(entry.Body)
+}
+
+// writeUpstreamResponse serves a freshly fetched upstream response.
+func writeUpstreamResponse(w http.ResponseWriter, resp *upstreamResponse, cacheResult string) {
+	copyHeader(w.Header(), resp.Header)
+	w.Header().Set("X-Cache", cacheResult)
+	w.WriteHeader(resp.Status)
+	w.Write(resp.Body)
+}
 
-	// Set response headers
-	for name, values := range resp.Header {
+func copyHeader(dst, src http.Header) {
+	for name, values := range src {
 		for _, value := range values {
-			w.Header().Add(name, value)
+			dst.Add(name, value)
 		}
 	}
-	w.WriteHeader(resp.StatusCode)
-	w.Write(body)
 }
 
 // handleHealth provides health check endpoint
@@ -176,10 +247,11 @@ func handleAnalytics(w http.ResponseWriter, r *http.Request) {
 	response := AnalyticsResponse{
 		Status: "success",
 		Data: map[string]interface{}{
-			"cache_hit_rate":       stats.CacheHitRate,
-			"total_requests":       stats.TotalRequests,
-			"ai_predictions":       stats.AIPredictions,
+			"cache_hit_rate":        stats.CacheHitRate,
+			"total_requests":        stats.TotalRequests,
+			"ai_predictions":        stats.AIPredictions,
 			"average_response_time": stats.AvgResponseTime,
+			"p95_response_time":     stats.P95ResponseTime,
 		},
 		Timestamp: time.Now().Format(time.RFC3339),
 	}
@@ -225,7 +297,7 @@ func handleCacheClear(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Clear all cache keys
-	keys, err := cache.RedisClient.Keys(context.Background(), "proxy:*").Result()
+	keys, err := cache.RedisClient.Keys(context.Background(), "proxy:*")
 	if err != nil {
 		http.Error(w, "Error clearing cache", http.StatusInternalServerError)
 		return
@@ -236,10 +308,14 @@ func handleCacheClear(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Reset statistics
-	cache.RedisClient.Set(context.Background(), "stats:total_requests", 0, 0)
-	cache.RedisClient.Set(context.Background(), "stats:cache_hits", 0, 0)
-	cache.RedisClient.Set(context.Background(), "stats:cache_misses", 0, 0)
-	cache.RedisClient.Set(context.Background(), "stats:ai_predictions", 0, 0)
+	cache.RedisClient.Set(context.Background(), "stats:total_requests", "0", 0)
+	cache.RedisClient.Set(context.Background(), "stats:cache_hits", "0", 0)
+	cache.RedisClient.Set(context.Background(), "stats:cache_misses", "0", 0)
+	cache.RedisClient.Set(context.Background(), "stats:ai_predictions", "0", 0)
+
+	// Let other instances know the cache and stats were cleared
+	cache.PublishCacheInvalidate("proxy:*", keys)
+	cache.PublishStatsReset()
 
 	w.Header().Set("Content-Type", "application/json")
 	response := map[string]interface{}{
@@ -258,7 +334,7 @@ func handleAIToggle(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get current AI status
-	currentStatus, err := cache.RedisClient.Get(context.Background(), "ai:enabled").Result()
+	currentStatus, err := cache.RedisClient.Get(context.Background(), "ai:enabled")
 	if err != nil {
 		currentStatus = "true" // Default to enabled
 	}
@@ -271,6 +347,9 @@ func handleAIToggle(w http.ResponseWriter, r *http.Request) {
 
 	cache.RedisClient.Set(context.Background(), "ai:enabled", newStatus, 0)
 
+	// Let other instances know AI mode changed
+	cache.PublishAIToggle(newStatus == "true")
+
 	w.Header().Set("Content-Type", "application/json")
 	response := map[string]interface{}{
 		"status":  "success",
@@ -289,19 +368,27 @@ func handleDashboard(w http.ResponseWriter, r *http.Request) {
 // getCacheStats retrieves cache statistics from Redis
 func getCacheStats() (*CacheStats, error) {
 	ctx := context.Background()
-	
-	totalRequests, _ := cache.RedisClient.Get(ctx, "stats:total_requests").Int64()
-	cacheHits, _ := cache.RedisClient.Get(ctx, "stats:cache_hits").Int64()
-	cacheMisses, _ := cache.RedisClient.Get(ctx, "stats:cache_misses").Int64()
-	aiPredictions, _ := cache.RedisClient.Get(ctx, "stats:ai_predictions").Int64()
+
+	totalRequests := getStatCounter(ctx, "stats:total_requests")
+	cacheHits := getStatCounter(ctx, "stats:cache_hits")
+	cacheMisses := getStatCounter(ctx, "stats:cache_misses")
+	aiPredictions := getStatCounter(ctx, "stats:ai_predictions")
 
 	var cacheHitRate float64
 	if totalRequests > 0 {
 		cacheHitRate = float64(cacheHits) / float64(totalRequests)
 	}
 
-	// Calculate average response time (simplified)
-	avgResponseTime := "45ms" // This would be calculated from actual response times
+	avgMs, p95Ms := responseTimeStats(ctx)
+	// Each resource also has a "<baseKey>:vary" index key under proxy:*;
+	// those are not cache entries.
+	entries := 0
+	for _, key := range getAllCacheKeys() {
+		if !strings.HasSuffix(key, ":vary") {
+			entries++
+		}
+	}
+	cacheEntriesGauge.Set(float64(entries))
 
 	return &CacheStats{
 		TotalRequests:   totalRequests,
@@ -309,15 +396,29 @@ func getCacheStats() (*CacheStats, error) {
 		CacheMisses:     cacheMisses,
 		CacheHitRate:    cacheHitRate,
 		AIPredictions:   aiPredictions,
-		AvgResponseTime: avgResponseTime,
+		AvgResponseTime: fmt.Sprintf("%.0fms", avgMs),
+		P95ResponseTime: fmt.Sprintf("%.0fms", p95Ms),
 	}, nil
 }
 
 // getAllCacheKeys retrieves all cache keys
 func getAllCacheKeys() []string {
-	keys, err := cache.RedisClient.Keys(context.Background(), "proxy:*").Result()
+	keys, err := cache.RedisClient.Keys(context.Background(), "proxy:*")
 	if err != nil {
 		return []string{}
 	}
 	return keys
 }
+
+// getStatCounter reads a stats counter, defaulting to 0 if absent or unset
+func getStatCounter(ctx context.Context, key string) int64 {
+	value, err := cache.RedisClient.Get(ctx, key)
+	if err != nil {
+		return 0
+	}
+	count, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0
+	}
+	return count
+}
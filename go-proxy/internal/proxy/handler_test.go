@@ -0,0 +1,206 @@
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"go-proxy/internal/cache"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func setupTestCache(t *testing.T) {
+	t.Helper()
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("failed to start miniredis: %v", err)
+	}
+	t.Cleanup(mr.Close)
+	cache.RedisClient = cache.NewGoRedisStore(mr.Addr())
+}
+
+func setupUpstream(t *testing.T, handler http.HandlerFunc) *int32 {
+	t.Helper()
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		handler(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	previous := upstreamTarget
+	upstreamTarget = server.URL
+	t.Cleanup(func() { upstreamTarget = previous })
+
+	return &hits
+}
+
+func doProxyRequest(method, path string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	rec := httptest.NewRecorder()
+	handleProxyRequest(rec, req)
+	return rec
+}
+
+func TestHandleProxyRequest_CacheMissThenHit(t *testing.T) {
+	setupTestCache(t)
+	hits := setupUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("hello"))
+	})
+
+	first := doProxyRequest(http.MethodGet, "/widgets", nil)
+	if first.Header().Get("X-Cache") != "MISS" || first.Body.String() != "hello" {
+		t.Fatalf("unexpected first response: %q cache=%q", first.Body.String(), first.Header().Get("X-Cache"))
+	}
+
+	second := doProxyRequest(http.MethodGet, "/widgets", nil)
+	if second.Header().Get("X-Cache") != "HIT" {
+		t.Fatalf("expected HIT, got %q", second.Header().Get("X-Cache"))
+	}
+	if second.Header().Get("Age") == "" {
+		t.Fatal("expected Age header on cache hit")
+	}
+	if got := atomic.LoadInt32(hits); got != 1 {
+		t.Fatalf("expected exactly 1 upstream call, got %d", got)
+	}
+}
+
+func TestHandleProxyRequest_ResponseNoStoreNeverCached(t *testing.T) {
+	setupTestCache(t)
+	hits := setupUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("secret"))
+	})
+
+	doProxyRequest(http.MethodGet, "/secret", nil)
+	doProxyRequest(http.MethodGet, "/secret", nil)
+
+	if got := atomic.LoadInt32(hits); got != 2 {
+		t.Fatalf("expected upstream hit on every request, got %d", got)
+	}
+}
+
+func TestHandleProxyRequest_NonCacheableStatusNotStored(t *testing.T) {
+	setupTestCache(t)
+	hits := setupUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("boom"))
+	})
+
+	doProxyRequest(http.MethodGet, "/broken", nil)
+	doProxyRequest(http.MethodGet, "/broken", nil)
+
+	if got := atomic.LoadInt32(hits); got != 2 {
+		t.Fatalf("expected 500 responses to never be cached, got %d upstream calls", got)
+	}
+}
+
+func TestHandleProxyRequest_VaryHeaderPicksVariant(t *testing.T) {
+	setupTestCache(t)
+	setupUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.Header().Set("Vary", "Accept-Language")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintf(w, "lang=%s", r.Header.Get("Accept-Language"))
+	})
+
+	en := doProxyRequest(http.MethodGet, "/greeting", map[string]string{"Accept-Language": "en"})
+	fr := doProxyRequest(http.MethodGet, "/greeting", map[string]string{"Accept-Language": "fr"})
+	enAgain := doProxyRequest(http.MethodGet, "/greeting", map[string]string{"Accept-Language": "en"})
+
+	if en.Body.String() != "lang=en" || fr.Body.String() != "lang=fr" {
+		t.Fatalf("unexpected variant bodies: en=%q fr=%q", en.Body.String(), fr.Body.String())
+	}
+	if enAgain.Header().Get("X-Cache") != "HIT" || enAgain.Body.String() != "lang=en" {
+		t.Fatalf("expected matching variant to hit cache, got %q body=%q", enAgain.Header().Get("X-Cache"), enAgain.Body.String())
+	}
+}
+
+func TestHandleProxyRequest_StaleEntryRevalidates(t *testing.T) {
+	setupTestCache(t)
+	hits := setupUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Cache-Control", "max-age=0")
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("content"))
+	})
+
+	first := doProxyRequest(http.MethodGet, "/doc", nil)
+	second := doProxyRequest(http.MethodGet, "/doc", nil)
+
+	if first.Header().Get("X-Cache") != "MISS" {
+		t.Fatalf("expected first request to miss, got %q", first.Header().Get("X-Cache"))
+	}
+	if second.Header().Get("X-Cache") != "REVALIDATED" || second.Body.String() != "content" {
+		t.Fatalf("expected revalidated hit with original body, got cache=%q body=%q", second.Header().Get("X-Cache"), second.Body.String())
+	}
+	if got := atomic.LoadInt32(hits); got != 2 {
+		t.Fatalf("expected one fetch plus one revalidation, got %d upstream calls", got)
+	}
+}
+
+func TestHandleProxyRequest_RequestNoCacheForcesRevalidation(t *testing.T) {
+	setupTestCache(t)
+	hits := setupUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Cache-Control", "max-age=600")
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("content"))
+	})
+
+	doProxyRequest(http.MethodGet, "/fresh", nil)
+	forced := doProxyRequest(http.MethodGet, "/fresh", map[string]string{"Cache-Control": "no-cache"})
+
+	if forced.Header().Get("X-Cache") != "REVALIDATED" {
+		t.Fatalf("expected no-cache request to force revalidation, got %q", forced.Header().Get("X-Cache"))
+	}
+	if got := atomic.LoadInt32(hits); got != 2 {
+		t.Fatalf("expected request no-cache to hit upstream, got %d calls", got)
+	}
+}
+
+func TestHandleProxyRequest_OnlyIfCachedWithoutEntry(t *testing.T) {
+	setupTestCache(t)
+	setupUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("should not be called"))
+	})
+
+	rec := doProxyRequest(http.MethodGet, "/uncached", map[string]string{"Cache-Control": "only-if-cached"})
+	if rec.Code != http.StatusGatewayTimeout {
+		t.Fatalf("expected 504 for only-if-cached miss, got %d", rec.Code)
+	}
+}
+
+func TestHandleProxyRequest_NonIdempotentMethodBypassesCache(t *testing.T) {
+	setupTestCache(t)
+	hits := setupUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("created"))
+	})
+
+	doProxyRequest(http.MethodPost, "/widgets", nil)
+	doProxyRequest(http.MethodPost, "/widgets", nil)
+
+	if got := atomic.LoadInt32(hits); got != 2 {
+		t.Fatalf("expected POST to always hit upstream, got %d calls", got)
+	}
+}
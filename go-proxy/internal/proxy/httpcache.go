@@ -0,0 +1,321 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cacheableStatuses lists the status codes this proxy stores: 200, 203, 300,
+// 301, 404 and 410. Other codes cacheable by default under HTTP (204, 206,
+// 405, 414, 501) are not stored.
+var cacheableStatuses = map[int]bool{
+	http.StatusOK:                   true,
+	http.StatusNonAuthoritativeInfo: true,
+	http.StatusMultipleChoices:      true,
+	http.StatusMovedPermanently:     true,
+	http.StatusNotFound:             true,
+	http.StatusGone:                 true,
+}
+
+// isCacheableMethod reports whether method is safe/idempotent enough to be
+// cached by default; everything else always goes straight to upstream.
+func isCacheableMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead
+}
+
+// cachedEntry is the in-Redis representation of a cached response.
+type cachedEntry struct {
+	Status   int
+	Header   http.Header
+	Body     []byte
+	StoredAt time.Time
+}
+
+func (e *cachedEntry) toFields(requestMethod, requestURL, requestHeaders string, responseTime time.Duration) map[string]string {
+	headerJSON, _ := json.Marshal(e.Header)
+	return map[string]string{
+		"request_method":   requestMethod,
+		"request_url":      requestURL,
+		"request_headers":  requestHeaders,
+		"response":         string(e.Body),
+		"response_headers": string(headerJSON),
+		"status":           strconv.Itoa(e.Status),
+		"purpose":          "empty",
+		"response_time":    responseTime.String(),
+		"timestamp":        e.StoredAt.Format(time.RFC3339),
+	}
+}
+
+func entryFromFields(fields map[string]string) (*cachedEntry, bool) {
+	raw, ok := fields["response_headers"]
+	if !ok {
+		return nil, false
+	}
+
+	header := http.Header{}
+	_ = json.Unmarshal([]byte(raw), &header)
+
+	status, err := strconv.Atoi(fields["status"])
+	if err != nil {
+		status = http.StatusOK
+	}
+
+	storedAt, err := time.Parse(time.RFC3339, fields["timestamp"])
+	if err != nil {
+		storedAt = time.Now()
+	}
+
+	return &cachedEntry{
+		Status:   status,
+		Header:   header,
+		Body:     []byte(fields["response"]),
+		StoredAt: storedAt,
+	}, true
+}
+
+// canonicalURL renders the request path with its query string sorted, so
+// "?b=2&a=1" and "?a=1&b=2" share a cache entry.
+func canonicalURL(r *http.Request) string {
+	query := r.URL.Query()
+	names := make([]string, 0, len(query))
+	for name := range query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf strings.Builder
+	buf.WriteString(r.URL.Path)
+	for i, name := range names {
+		values := append([]string(nil), query[name]...)
+		sort.Strings(values)
+		for j, value := range values {
+			if i == 0 && j == 0 {
+				buf.WriteByte('?')
+			} else {
+				buf.WriteByte('&')
+			}
+			buf.WriteString(url.QueryEscape(name))
+			buf.WriteByte('=')
+			buf.WriteString(url.QueryEscape(value))
+		}
+	}
+	return buf.String()
+}
+
+// baseCacheKey identifies a resource by method and canonical URL, before any
+// Vary-driven variation is applied.
+func baseCacheKey(r *http.Request) string {
+	return fmt.Sprintf("proxy:%s:%s", r.Method, canonicalURL(r))
+}
+
+// noVarySentinel is stored at a resource's :vary key once we've confirmed its
+// response carries no Vary header, so later requests can tell "confirmed no
+// Vary" apart from "Vary not learned yet" (an absent :vary key) - the latter
+// isn't safe to coalesce concurrent requests against without also accounting
+// for their headers, since we don't yet know which ones the response varies
+// on. See coalesceKeyFor.
+const noVarySentinel = "-"
+
+// encodeVaryIndex renders vary (a response's raw Vary header, possibly
+// empty) for storage at a :vary key.
+func encodeVaryIndex(vary string) string {
+	if vary == "" {
+		return noVarySentinel
+	}
+	return vary
+}
+
+func splitVaryHeader(raw string) []string {
+	parts := strings.Split(raw, ",")
+	names := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if name := strings.TrimSpace(part); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// variantKey derives the Vary-aware cache key for r, given the set of
+// header names the stored response said it varies on.
+func variantKey(baseKey string, r *http.Request, varyHeaders []string) string {
+	if len(varyHeaders) == 0 {
+		return baseKey
+	}
+
+	sorted := append([]string(nil), varyHeaders...)
+	sort.Strings(sorted)
+
+	var buf strings.Builder
+	buf.WriteString(baseKey)
+	buf.WriteString(":vary")
+	for _, name := range sorted {
+		buf.WriteByte(':')
+		buf.WriteString(strings.ToLower(name))
+		buf.WriteByte('=')
+		buf.WriteString(r.Header.Get(name))
+	}
+	return buf.String()
+}
+
+// parseCacheControl splits a Cache-Control header into lower-cased
+// directives, e.g. "max-age=60, must-revalidate" -> {"max-age":"60",
+// "must-revalidate":""}.
+func parseCacheControl(header string) map[string]string {
+	directives := make(map[string]string)
+	for _, part := range strings.Split(header, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		if idx := strings.Index(part, "="); idx >= 0 {
+			name := strings.ToLower(strings.TrimSpace(part[:idx]))
+			value := strings.Trim(strings.TrimSpace(part[idx+1:]), `"`)
+			directives[name] = value
+		} else {
+			directives[strings.ToLower(part)] = ""
+		}
+	}
+	return directives
+}
+
+func cacheControlSeconds(directives map[string]string, name string) (time.Duration, bool) {
+	value, ok := directives[name]
+	if !ok {
+		return 0, false
+	}
+	seconds, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return time.Duration(seconds) * time.Second, true
+}
+
+// requestDirectives is the subset of request Cache-Control directives this
+// proxy understands.
+type requestDirectives struct {
+	noCache      bool
+	noStore      bool
+	onlyIfCached bool
+	maxAge       time.Duration
+	hasMaxAge    bool
+	minFresh     time.Duration
+}
+
+func parseRequestDirectives(r *http.Request) requestDirectives {
+	cc := parseCacheControl(r.Header.Get("Cache-Control"))
+
+	d := requestDirectives{}
+	if _, ok := cc["no-cache"]; ok {
+		d.noCache = true
+	}
+	if _, ok := cc["no-store"]; ok {
+		d.noStore = true
+	}
+	if _, ok := cc["only-if-cached"]; ok {
+		d.onlyIfCached = true
+	}
+	if maxAge, ok := cacheControlSeconds(cc, "max-age"); ok {
+		d.maxAge = maxAge
+		d.hasMaxAge = true
+	}
+	if minFresh, ok := cacheControlSeconds(cc, "min-fresh"); ok {
+		d.minFresh = minFresh
+	}
+
+	// Pragma: no-cache is the legacy HTTP/1.0 spelling of Cache-Control: no-cache.
+	if !d.noCache && r.Header.Get("Pragma") == "no-cache" {
+		d.noCache = true
+	}
+
+	return d
+}
+
+// freshness holds the computed age and freshness lifetime of a cached
+// response, per RFC 7234 §4.2.
+type freshness struct {
+	age               time.Duration
+	freshnessLifetime time.Duration
+}
+
+func computeFreshness(header http.Header, storedAt, now time.Time) freshness {
+	date := storedAt
+	if raw := header.Get("Date"); raw != "" {
+		if parsed, err := http.ParseTime(raw); err == nil {
+			date = parsed
+		}
+	}
+
+	age := now.Sub(date)
+	if age < 0 {
+		age = 0
+	}
+	if raw := header.Get("Age"); raw != "" {
+		if secs, err := strconv.ParseInt(raw, 10, 64); err == nil {
+			if upstreamAge := time.Duration(secs) * time.Second; upstreamAge > age {
+				age = upstreamAge
+			}
+		}
+	}
+
+	cc := parseCacheControl(header.Get("Cache-Control"))
+
+	lifetime, ok := cacheControlSeconds(cc, "s-maxage")
+	if !ok {
+		lifetime, ok = cacheControlSeconds(cc, "max-age")
+	}
+	if !ok {
+		if raw := header.Get("Expires"); raw != "" {
+			if expires, err := http.ParseTime(raw); err == nil {
+				lifetime = expires.Sub(date)
+				ok = true
+			}
+		}
+	}
+	if !ok || lifetime < 0 {
+		// No explicit freshness information: treat the entry as already
+		// stale so it is always revalidated before being served.
+		lifetime = 0
+	}
+
+	return freshness{age: age, freshnessLifetime: lifetime}
+}
+
+func (f freshness) isFresh(minFresh time.Duration) bool {
+	return f.freshnessLifetime-f.age >= minFresh
+}
+
+// responseCacheControl reports the response-side directives relevant to
+// deciding whether (and how) to store an entry.
+type responseCacheControl struct {
+	noStore        bool
+	private        bool
+	mustRevalidate bool
+}
+
+func parseResponseCacheControl(header http.Header) responseCacheControl {
+	cc := parseCacheControl(header.Get("Cache-Control"))
+	_, noStore := cc["no-store"]
+	_, private := cc["private"]
+	_, mustRevalidate := cc["must-revalidate"]
+	return responseCacheControl{noStore: noStore, private: private, mustRevalidate: mustRevalidate}
+}
+
+// isStorable reports whether a freshly fetched response may be cached at
+// all, independent of the request that triggered the fetch.
+func isStorable(method string, status int, header http.Header) bool {
+	if !isCacheableMethod(method) || !cacheableStatuses[status] {
+		return false
+	}
+	if header.Get("Vary") == "*" {
+		return false
+	}
+	cc := parseResponseCacheControl(header)
+	return !cc.noStore && !cc.private
+}
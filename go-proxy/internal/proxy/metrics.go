@@ -0,0 +1,88 @@
+package proxy
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"time"
+
+	"go-proxy/internal/cache"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// responseTimeWindow bounds the rolling sample window kept in Redis under
+// stats:response_times, used to compute AvgResponseTime/P95ResponseTime.
+const responseTimeWindow = 1000
+
+var (
+	requestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "proxy_requests_total",
+		Help: "Total proxied requests, labeled by cache result, method and upstream status code.",
+	}, []string{"result", "method", "status"})
+
+	upstreamDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "proxy_upstream_duration_seconds",
+		Help:    "Latency of upstream fetches.",
+		Buckets: prometheus.DefBuckets,
+	})
+
+	totalDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "proxy_total_duration_seconds",
+		Help:    "End-to-end latency of proxied requests, from receipt to response written.",
+		Buckets: prometheus.DefBuckets,
+	})
+
+	cacheEntriesGauge = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "proxy_cache_entries",
+		Help: "Number of entries currently stored in the cache.",
+	})
+)
+
+// recordRequest updates proxy_requests_total/proxy_total_duration_seconds,
+// pushes the elapsed time onto the stats:response_times rolling window, and
+// publishes a proxyEvent for any connected /events subscribers. result is
+// one of "hit", "miss", "error".
+func recordRequest(result, method, path string, status int, start time.Time) {
+	elapsed := time.Since(start)
+	requestsTotal.WithLabelValues(result, method, strconv.Itoa(status)).Inc()
+	totalDuration.Observe(elapsed.Seconds())
+	cache.RedisClient.ListPush(cache.GetContext(), "stats:response_times", strconv.FormatInt(elapsed.Milliseconds(), 10), responseTimeWindow)
+	publishProxyEvent(method, path, result, status, elapsed)
+}
+
+// responseTimeStats computes the average and p95 of the response-time
+// samples in stats:response_times, in milliseconds. Both are 0 if no
+// samples have been recorded yet.
+func responseTimeStats(ctx context.Context) (avgMs, p95Ms float64) {
+	raw, err := cache.RedisClient.ListRange(ctx, "stats:response_times")
+	if err != nil || len(raw) == 0 {
+		return 0, 0
+	}
+
+	samples := make([]float64, 0, len(raw))
+	for _, v := range raw {
+		if ms, err := strconv.ParseFloat(v, 64); err == nil {
+			samples = append(samples, ms)
+		}
+	}
+	if len(samples) == 0 {
+		return 0, 0
+	}
+
+	sort.Float64s(samples)
+
+	var sum float64
+	for _, ms := range samples {
+		sum += ms
+	}
+	avgMs = sum / float64(len(samples))
+
+	idx := int(float64(len(samples)) * 0.95)
+	if idx >= len(samples) {
+		idx = len(samples) - 1
+	}
+	p95Ms = samples[idx]
+	return avgMs, p95Ms
+}
@@ -0,0 +1,57 @@
+package proxy
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go-proxy/internal/cache"
+)
+
+func TestResponseTimeStatsComputesAvgAndP95(t *testing.T) {
+	setupTestCache(t)
+	ctx := context.Background()
+
+	samples := []string{"10", "20", "30", "40", "100"}
+	for _, ms := range samples {
+		if err := cache.RedisClient.ListPush(ctx, "stats:response_times", ms, responseTimeWindow); err != nil {
+			t.Fatalf("ListPush: %v", err)
+		}
+	}
+
+	avg, p95 := responseTimeStats(ctx)
+	if avg != 40 {
+		t.Fatalf("avg = %v, want 40", avg)
+	}
+	if p95 != 100 {
+		t.Fatalf("p95 = %v, want 100", p95)
+	}
+}
+
+func TestResponseTimeStatsEmpty(t *testing.T) {
+	setupTestCache(t)
+
+	avg, p95 := responseTimeStats(context.Background())
+	if avg != 0 || p95 != 0 {
+		t.Fatalf("expected zero stats with no samples, got avg=%v p95=%v", avg, p95)
+	}
+}
+
+func TestMetricsEndpointServesPrometheusFormat(t *testing.T) {
+	setupTestCache(t)
+
+	recordRequest("hit", "GET", "/x", 200, time.Now())
+
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	w := httptest.NewRecorder()
+	ProxyHandler(w, req)
+
+	if w.Code != 200 {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	if body := w.Body.String(); !strings.Contains(body, "proxy_requests_total") {
+		t.Fatalf("expected proxy_requests_total in /metrics output, got: %s", body)
+	}
+}
@@ -11,7 +11,7 @@ import (
 // ExecuteAndUpdateRequest...
 func ExecuteAndUpdateRequest(cacheKey string) error {
 	// Sprawdzenie, czy wpis w Redisie ma pole "purpose" ustawione na "refresh"
-	data, err := cache.RedisClient.HGetAll(context.Background(), cacheKey).Result()
+	data, err := cache.RedisClient.HGetAll(context.Background(), cacheKey)
 	if err != nil {
 		return fmt.Errorf("error fetching data from Redis: %w", err)
 	}
@@ -43,10 +43,10 @@ func ExecuteAndUpdateRequest(cacheKey string) error {
 	}
 
 	// Aktualizacja danych w Redisie (response + ustawienie purpose na empty)
-	err = cache.RedisClient.HMSet(context.Background(), cacheKey, map[string]interface{}{
+	err = cache.RedisClient.HSet(context.Background(), cacheKey, map[string]string{
 		"response": string(body),
 		"purpose":  "empty",
-	}).Err()
+	})
 	if err != nil {
 		return fmt.Errorf("error updating Redis: %w", err)
 	}
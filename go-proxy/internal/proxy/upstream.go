@@ -0,0 +1,102 @@
+package proxy
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// upstreamTarget is the backend this proxy forwards requests to. It is a
+// package variable (rather than config-driven) so tests can point it at an
+// httptest.Server.
+var upstreamTarget = "http://httpbin.org"
+
+// upstreamResponse is the result of a fetch against upstreamTarget.
+type upstreamResponse struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+func targetURLFor(r *http.Request) string {
+	targetURL := upstreamTarget + r.URL.Path
+	if r.URL.RawQuery != "" {
+		targetURL += "?" + r.URL.RawQuery
+	}
+	return targetURL
+}
+
+// fetchUpstream performs a plain, unconditional request to upstreamTarget.
+func fetchUpstream(r *http.Request) (*upstreamResponse, error) {
+	defer func(start time.Time) { upstreamDuration.Observe(time.Since(start).Seconds()) }(time.Now())
+
+	req, err := http.NewRequest(r.Method, targetURLFor(r), r.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+	for name, values := range r.Header {
+		for _, value := range values {
+			req.Header.Add(name, value)
+		}
+	}
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error sending request to target server: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response: %w", err)
+	}
+
+	return &upstreamResponse{Status: resp.StatusCode, Header: resp.Header, Body: body}, nil
+}
+
+// revalidateUpstream issues a conditional request for a stale cache entry
+// using its ETag/Last-Modified. On 304 Not Modified it returns the entry's
+// body with headers refreshed from the 304 response.
+func revalidateUpstream(entry *cachedEntry, r *http.Request) (*upstreamResponse, bool) {
+	req, err := http.NewRequest(r.Method, targetURLFor(r), nil)
+	if err != nil {
+		return nil, false
+	}
+	for name, values := range r.Header {
+		for _, value := range values {
+			req.Header.Add(name, value)
+		}
+	}
+	if etag := entry.Header.Get("ETag"); etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+	if lastModified := entry.Header.Get("Last-Modified"); lastModified != "" {
+		req.Header.Set("If-Modified-Since", lastModified)
+	}
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, false
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified {
+		header := entry.Header.Clone()
+		for name, values := range resp.Header {
+			header.Del(name)
+			for _, value := range values {
+				header.Add(name, value)
+			}
+		}
+		return &upstreamResponse{Status: entry.Status, Header: header, Body: entry.Body}, true
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, false
+	}
+	return &upstreamResponse{Status: resp.StatusCode, Header: resp.Header, Body: body}, true
+}
@@ -2,12 +2,17 @@ package config
 
 import (
 	"os"
+	"strconv"
+	"time"
 )
 
 // Config struktura dla konfiguracji aplikacji
 type Config struct {
-	RedisAddr  string
-	ServerPort string
+	RedisAddr        string
+	ServerPort       string
+	RedisSyncEnabled bool
+	LocalCacheTTL    time.Duration
+	CacheBackend     string
 }
 
 // LoadConfig wczytuje konfigurację z plików środowiskowych lub domyślną
@@ -22,8 +27,21 @@ func LoadConfig() Config {
 		serverPort = "8080" // Domyślny port serwera
 	}
 
+	redisSyncEnabled, err := strconv.ParseBool(os.Getenv("REDIS_SYNC_ENABLED"))
+	if err != nil {
+		redisSyncEnabled = false // Domyślnie synchronizacja między instancjami jest wyłączona
+	}
+
+	localCacheTTLSeconds, err := strconv.Atoi(os.Getenv("LOCAL_CACHE_TTL_SECONDS"))
+	if err != nil {
+		localCacheTTLSeconds = 600 // Domyślny czas życia lokalnego cache po stronie klienta
+	}
+
 	return Config{
-		RedisAddr:  redisAddr,
-		ServerPort: serverPort,
+		RedisAddr:        redisAddr,
+		ServerPort:       serverPort,
+		RedisSyncEnabled: redisSyncEnabled,
+		LocalCacheTTL:    time.Duration(localCacheTTLSeconds) * time.Second,
+		CacheBackend:     os.Getenv("CACHE_BACKEND"), // np. "redis://host:6379", "memory://?size=1024", "multi://memory,redis"
 	}
 }